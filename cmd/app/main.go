@@ -1,37 +1,226 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"homework1/internal/auth"
 	"homework1/internal/config"
 	"homework1/internal/database"
+	"homework1/internal/health"
+	"homework1/internal/logging"
+	"homework1/internal/metrics"
+	"homework1/internal/middleware"
+	"homework1/internal/models"
 	"homework1/internal/repository"
 	"homework1/internal/routers"
 	"homework1/internal/services"
-	"log"
 )
 
 func main() {
 
-	// load configuration
-	config := config.LoadConfig()
+	ctx := context.Background()
+
+	// load configuration, flags override the environment and the config file
+	config_flags := config.RegisterFlags(flag.CommandLine)
+	print_config := flag.Bool("print-config", false, "print the effective configuration and where each value came from, then exit")
+	flag.Parse()
+
+	// records are JSON from the start, the level is known once the
+	// configuration is loaded
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+	config, err := config.Load(config_flags)
+	if err != nil {
+		logging.Fatal("invalid configuration", "error", err)
+	}
+	if *print_config {
+		if err := config.Print(os.Stdout); err != nil {
+			logging.Fatal("failed to print configuration", "error", err)
+		}
+		return
+	}
+	logger := logging.New(os.Stdout, config.LogLevel)
+	slog.SetDefault(logger)
 
 	// create database connection
-	db := database.InitDB(config.DatabaseDN)
+	db := database.InitDB(config)
+	database.RequireSchema(db, config.DatabaseDriver, config.AllowPendingMigrations)
 
-	log.Println("Database connection successful", db)
+	slog.Info("connected to database", "driver", config.DatabaseDriver)
 
 	user_repository := repository.NewUserRepository(db)
 	product_repository := repository.NewProductRepository(db)
+	refresh_token_repository := repository.NewRefreshTokenRepository(db)
+	rbac_repository := repository.NewRBACRepository(db)
+	idempotency_repository := repository.NewIdempotencyRepository(db)
+
+	// validated with the rest of the configuration
+	product_policy := repository.ProductPolicy(config.UserDeletePolicy)
+
+	user_service := services.NewUserService(user_repository, product_policy)
+	product_searcher, err := repository.NewProductSearcher(db, config.DatabaseDriver)
+	if err != nil {
+		logging.Fatal("failed to set up product search", "error", err)
+	}
+	product_service := services.NewProductService(product_repository, product_searcher)
+	idempotency_service := services.NewIdempotencyService(idempotency_repository, config.IdempotencyKeyTTL)
+	import_service := services.NewImportService(product_repository, config.ImportJobRetention)
+	import_limits := routers.ImportLimits{SyncRows: config.ImportSyncRows, MaxRows: config.ImportMaxRows, MaxBodySize: int64(config.ImportMaxBodySize)}
+
+	if config.BootstrapAdminEmail != "" {
+		if _, err := user_repository.GetByEmail(ctx, config.BootstrapAdminEmail); err != nil {
+			_, err := user_service.CreateUser(ctx, model.User{
+				FirstName: "Admin",
+				LastName:  "Admin",
+				Email:     config.BootstrapAdminEmail,
+				Password:  config.BootstrapAdminPassword,
+				Role:      model.RoleAdmin,
+			})
+			if err != nil {
+				logging.Fatal("failed to create bootstrap admin", "error", err)
+			}
+			slog.Info("created bootstrap admin", "email", config.BootstrapAdminEmail)
+		}
+	}
+
+	rbac_service, err := services.NewRBACService(ctx, rbac_repository)
+	if err != nil {
+		logging.Fatal("failed to load role permissions", "error", err)
+	}
+
+	// without configured keys fall back to a random one, tokens will not
+	// survive a restart
+	if len(config.JWTSigningKeys) == 0 {
+		slog.Warn("no signing keys are configured, using an ephemeral signing key")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			logging.Fatal("failed to generate signing key", "error", err)
+		}
+		config.JWTSigningKeys = map[string]string{"ephemeral": hex.EncodeToString(secret)}
+		config.JWTActiveKeyID = "ephemeral"
+	}
+
+	signer, err := auth.NewSigner(config.JWTSigningKeys, config.JWTActiveKeyID, config.AccessTokenTTL)
+	if err != nil {
+		logging.Fatal("failed to configure token signer", "error", err)
+	}
+
+	auth_service := services.NewAuthService(user_service, refresh_token_repository, signer, config.RefreshTokenTTL)
+
+	// metrics are kept in-process and scraped from /metrics
+	registry := metrics.NewRegistry()
+	http_metrics := metrics.NewHTTP(registry)
+	if err := metrics.RegisterDB(registry, db); err != nil {
+		logging.Fatal("failed to set up database metrics", "error", err)
+	}
+	registerDomainMetrics(registry, product_repository, user_repository)
+
+	// readiness checks, the disk only matters when the database is a local
+	// SQLite file
+	health_checker := health.NewChecker(config.HealthCheckTimeout)
+	health_checker.Add("database", health.Database(db))
+	health_checker.Add("migrations", health.Migrations(db, config.DatabaseDriver, config.AllowPendingMigrations))
+	if path, ok := database.SQLiteFile(config.DSN()); config.DatabaseDriver == "sqlite" && ok {
+		health_checker.Add("disk", health.DiskSpace(filepath.Dir(path), uint64(config.HealthMinFreeDisk)))
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Logger(logger), middleware.Metrics(http_metrics), middleware.Recovery(logger))
+	routers.SetupRouter(router, user_service, product_service, auth_service, rbac_service, idempotency_service, import_service, import_limits, signer, config.PurgeRetention, registry, http_metrics, health_checker)
+
+	listener, err := net.Listen("tcp", config.HTTPAddr)
+	if err != nil {
+		logging.Fatal("failed to start server", "error", err)
+	}
+
+	// Start server, SIGTERM or SIGINT stop it. A second signal is not
+	// caught anymore and kills the process.
+	signal_ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(signal_ctx, stop)
+
+	err = run(signal_ctx, config, listener, app{
+		handler: router,
+		checker: health_checker,
+		imports: import_service,
+		db:      db,
+	})
+	if err != nil {
+		logging.Fatal("server failed", "error", err)
+	}
+}
 
-	user_service := services.NewUserService(user_repository)
-	product_service := services.NewProductService(product_repository)
+// app is what run serves and then shuts down
+type app struct {
+	handler http.Handler
+	checker *health.Checker
+	imports services.ImportService
+	db      *gorm.DB
+}
 
-	router := gin.Default()
-	routers.SetupRouter(router, user_service, product_service)
+// run serves the app on listener until ctx is done, then shuts it down:
+// readiness fails for the drain delay, the server stops accepting
+// connections and waits for in-flight requests, import jobs get what is left
+// of the shutdown timeout, and the database is closed last.
+func run(ctx context.Context, config *config.Config, listener net.Listener, app app) error {
+	server := &http.Server{
+		Handler:           app.handler,
+		ReadTimeout:       config.HTTPReadTimeout,
+		ReadHeaderTimeout: config.HTTPReadHeaderTimeout,
+		WriteTimeout:      config.HTTPWriteTimeout,
+		IdleTimeout:       config.HTTPIdleTimeout,
+		MaxHeaderBytes:    int(config.HTTPMaxHeaderBytes),
+	}
 
-	// Start server
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	serve_err := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", listener.Addr().String())
+		serve_err <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serve_err:
+		if closeErr := database.Close(app.db); closeErr != nil {
+			slog.Error("failed to close database", "error", closeErr)
+		}
+		return err
+	case <-ctx.Done():
 	}
 
+	// fail readiness first so that load balancers stop sending traffic while
+	// connections are still accepted
+	app.checker.Drain()
+	slog.Info("shutting down, failing readiness", "delay", config.ShutdownDrainDelay.String())
+	time.Sleep(config.ShutdownDrainDelay)
+
+	// stop accepting connections and let in-flight requests and import jobs
+	// finish
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", config.ShutdownTimeout.String())
+	shutdown_ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdown_ctx); err != nil {
+		slog.Error("failed to drain requests", "error", err)
+	}
+	if err := app.imports.Wait(shutdown_ctx); err != nil {
+		slog.Error("abandoning running import jobs", "error", err)
+	}
+	if err := database.Close(app.db); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+	slog.Info("server stopped")
+	return nil
 }
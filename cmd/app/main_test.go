@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"homework1/internal/config"
+	"homework1/internal/health"
+	"homework1/internal/services"
+)
+
+func TestRunShutsDownGracefully(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the slow handler holds its request open until it is released
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	cfg := &config.Config{ShutdownTimeout: 10 * time.Second, HTTPMaxHeaderBytes: 1 << 20}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, cfg, listener, app{
+			handler: mux,
+			checker: health.NewChecker(time.Second),
+			imports: services.NewImportService(nil, time.Hour),
+			db:      db,
+		})
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		slow <- result{res.StatusCode, string(body), err}
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow request never reached the handler")
+	}
+
+	cancel()
+
+	// the listener closes while the slow request is still running, a dial
+	// racing the close may still be accepted or reset
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			break
+		}
+		if err == nil {
+			conn.Close()
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("new connections are not refused after shutdown began: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("run returned %v before the in-flight request finished", err)
+	default:
+	}
+	if err := sqlDB.Ping(); err != nil {
+		t.Errorf("database closed while a request was in flight: %v", err)
+	}
+
+	close(release)
+	res := <-slow
+	if res.err != nil || res.status != http.StatusOK || res.body != "done" {
+		t.Errorf("slow request got %d %q, %v, want 200 done", res.status, res.body, res.err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the last request")
+	}
+	if err := sqlDB.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("ping after run: %v, want the database closed", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"homework1/internal/metrics"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+// registerDomainMetrics adds gauges read from the database on every scrape,
+// deleted rows are not counted
+func registerDomainMetrics(registry *metrics.Registry, products repository.ProductRepository, users repository.UserRepository) {
+	registry.NewGaugeFunc("products", "Products in the catalog.", nil, func(ctx context.Context, report metrics.Report) error {
+		totals, err := products.Totals(ctx)
+		if err != nil {
+			return err
+		}
+		report(float64(totals.Count))
+		return nil
+	})
+	registry.NewGaugeFunc("products_stock_quantity", "Units in stock over all products.", nil, func(ctx context.Context, report metrics.Report) error {
+		totals, err := products.Totals(ctx)
+		if err != nil {
+			return err
+		}
+		report(float64(totals.Quantity))
+		return nil
+	})
+	registry.NewGaugeFunc("users", "Users by role.", []string{"role"}, func(ctx context.Context, report metrics.Report) error {
+		counts, err := users.CountByRole(ctx)
+		if err != nil {
+			return err
+		}
+		for _, role := range model.Roles {
+			report(float64(counts[role]), role)
+		}
+		return nil
+	})
+}
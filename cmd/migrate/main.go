@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"homework1/internal/config"
+	"homework1/internal/database"
+	"homework1/internal/migrations"
+)
+
+const usage = `usage: migrate [flags] <command> [args]
+
+commands:
+  up [version]     apply pending migrations, up to version if given
+  down [steps]     roll back the last applied migration, or the last steps
+  status           list migrations and whether they are applied
+  create <name>    add an empty up/down pair for every database driver
+`
+
+func main() {
+	config_flags := config.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := config.Load(config_flags)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	command, arg := flag.Arg(0), flag.Arg(1)
+
+	// create only touches files, no database needed
+	if command == "create" {
+		if arg == "" {
+			log.Fatal("create needs a migration name")
+		}
+		created, err := migrations.Create(migrations.SourceDir, arg)
+		for _, file := range created {
+			fmt.Println("created", file)
+		}
+		if err != nil {
+			log.Fatalf("failed to create migration: %v", err)
+		}
+		return
+	}
+
+	db := database.InitDB(config)
+	migrator, err := migrations.New(db, config.DatabaseDriver)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+
+	switch command {
+	case "up":
+		var target int64
+		if arg != "" {
+			if target, err = strconv.ParseInt(arg, 10, 64); err != nil {
+				log.Fatalf("invalid version %q", arg)
+			}
+		}
+		done, err := migrator.Up(target)
+		for _, m := range done {
+			if m.Skipped {
+				fmt.Printf("skipped %04d_%s, its condition does not hold\n", m.Version, m.Name)
+				continue
+			}
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			fmt.Println("nothing to apply")
+		}
+
+	case "down":
+		steps := 1
+		if arg != "" {
+			if steps, err = strconv.Atoi(arg); err != nil || steps < 1 {
+				log.Fatalf("invalid steps %q", arg)
+			}
+		}
+		done, err := migrator.Down(steps)
+		for _, m := range done {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
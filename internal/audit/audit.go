@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the ID of the user making changes
+func WithActor(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFrom returns the user ID stored by WithActor
+func ActorFrom(ctx context.Context) (uuid.UUID, bool) {
+	if ctx == nil {
+		return uuid.Nil, false
+	}
+	userID, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+// RegisterCallbacks fills the CreatedBy and UpdatedBy fields of any model
+// that has them from the actor in the statement context. Timestamps are
+// already handled by GORM through CreatedAt and UpdatedAt.
+func RegisterCallbacks(db *gorm.DB) error {
+	err := db.Callback().Create().Before("gorm:create").Register("audit:create", func(tx *gorm.DB) {
+		actor, ok := ActorFrom(tx.Statement.Context)
+		if !ok || tx.Statement.Schema == nil {
+			return
+		}
+		setActor(tx, "CreatedBy", actor)
+		setActor(tx, "UpdatedBy", actor)
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Callback().Update().Before("gorm:update").Register("audit:update", func(tx *gorm.DB) {
+		actor, ok := ActorFrom(tx.Statement.Context)
+		if !ok || tx.Statement.Schema == nil || tx.Statement.Schema.LookUpField("UpdatedBy") == nil {
+			return
+		}
+		// SetColumn covers Save with a struct as well as Update and
+		// Updates with a column map
+		tx.Statement.SetColumn("UpdatedBy", &actor, true)
+	})
+}
+
+// setActor writes the actor into every row being created
+func setActor(tx *gorm.DB, name string, actor uuid.UUID) {
+	field := tx.Statement.Schema.LookUpField(name)
+	if field == nil {
+		return
+	}
+
+	rv := tx.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			setField(tx, field, reflect.Indirect(rv.Index(i)), actor)
+		}
+	case reflect.Struct:
+		setField(tx, field, rv, actor)
+	}
+}
+
+func setField(tx *gorm.DB, field *schema.Field, rv reflect.Value, actor uuid.UUID) {
+	if _, zero := field.ValueOf(tx.Statement.Context, rv); !zero {
+		return
+	}
+	if err := field.Set(tx.Statement.Context, rv, &actor); err != nil {
+		tx.AddError(err)
+	}
+}
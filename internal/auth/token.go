@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Claims carried by an access token
+type Claims struct {
+	Subject   uuid.UUID `json:"sub"`
+	Role      string    `json:"role"`
+	ID        string    `json:"jti"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// Signer issues and verifies HS256 JWTs. Tokens are signed with the active
+// key and verified with whichever key their kid header names.
+type Signer struct {
+	keys     map[string][]byte
+	activeID string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func NewSigner(keys map[string]string, activeID string, ttl time.Duration) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	s := &Signer{keys: map[string][]byte{}, activeID: activeID, ttl: ttl, now: time.Now}
+	for kid, secret := range keys {
+		s.keys[kid] = []byte(secret)
+	}
+
+	if s.activeID == "" && len(keys) == 1 {
+		for kid := range keys {
+			s.activeID = kid
+		}
+	}
+	if _, ok := s.keys[s.activeID]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", s.activeID)
+	}
+
+	return s, nil
+}
+
+// Sign returns a signed access token for the given user
+func (s *Signer) Sign(userID uuid.UUID, role string) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+
+	h, err := encodeSegment(header{Alg: "HS256", Typ: "JWT", Kid: s.activeID})
+	if err != nil {
+		return "", expiresAt, err
+	}
+	c, err := encodeSegment(Claims{
+		Subject:   userID,
+		Role:      role,
+		ID:        uuid.NewString(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", expiresAt, err
+	}
+
+	unsigned := h + "." + c
+	return unsigned + "." + sign(s.keys[s.activeID], unsigned), expiresAt, nil
+}
+
+// Parse verifies the token signature and expiry and returns its claims
+func (s *Signer) Parse(token string) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil || h.Alg != "HS256" {
+		return claims, ErrInvalidToken
+	}
+	key, ok := s.keys[h.Kid]
+	if !ok {
+		return claims, ErrUnknownKey
+	}
+
+	expected := sign(key, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return claims, ErrInvalidToken
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return claims, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+func sign(key []byte, data string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeSegment(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestSigner(t *testing.T, keys map[string]string, activeID string) *Signer {
+	t.Helper()
+	s, err := NewSigner(keys, activeID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSignParse(t *testing.T) {
+	s := newTestSigner(t, map[string]string{"a": "first-signing-key"}, "")
+	user := uuid.New()
+	token, expiresAt, err := s.Sign(user, "seller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != user || claims.Role != "seller" || claims.ExpiresAt != expiresAt.Unix() || claims.ID == "" {
+		t.Errorf("claims %+v do not match what was signed", claims)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	s := newTestSigner(t, map[string]string{"a": "first-signing-key"}, "a")
+	token, _, err := s.Sign(uuid.New(), "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	segment := func(v string) string { return base64.RawURLEncoding.EncodeToString([]byte(v)) }
+
+	other := newTestSigner(t, map[string]string{"a": "another-signing-key"}, "a")
+	foreign, _, _ := other.Sign(uuid.New(), "admin")
+	unknownKid := newTestSigner(t, map[string]string{"b": "first-signing-key"}, "b")
+	unknown, _, _ := unknownKid.Sign(uuid.New(), "admin")
+
+	// the claims of token with the role raised to admin
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	raised := segment(strings.Replace(string(claims), `"role":"customer"`, `"role":"admin"`, 1))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"empty", "", ErrInvalidToken},
+		{"two segments", parts[0] + "." + parts[1], ErrInvalidToken},
+		{"tampered claims", parts[0] + "." + raised + "." + parts[2], ErrInvalidToken},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), ErrInvalidToken},
+		{"no signature", parts[0] + "." + parts[1] + ".", ErrInvalidToken},
+		{"signed with another secret", foreign, ErrInvalidToken},
+		{"unknown kid", unknown, ErrUnknownKey},
+		{"alg none", segment(`{"alg":"none","typ":"JWT","kid":"a"}`) + "." + parts[1] + ".", ErrInvalidToken},
+		{"header not JSON", segment("{") + "." + parts[1] + "." + parts[2], ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		if _, err := s.Parse(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Parse = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseExpired(t *testing.T) {
+	s := newTestSigner(t, map[string]string{"a": "first-signing-key"}, "a")
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	token, _, err := s.Sign(uuid.New(), "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.now = func() time.Time { return now.Add(time.Minute - time.Second) }
+	if _, err := s.Parse(token); err != nil {
+		t.Errorf("a second before expiry: %v", err)
+	}
+	s.now = func() time.Time { return now.Add(time.Minute) }
+	if _, err := s.Parse(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("at expiry: %v, want ErrExpiredToken", err)
+	}
+}
+
+// TestKeyRotation signs with a new active key while tokens of the previous
+// one stay valid until it is removed
+func TestKeyRotation(t *testing.T) {
+	before := newTestSigner(t, map[string]string{"old": "old-signing-key"}, "old")
+	oldToken, _, _ := before.Sign(uuid.New(), "customer")
+
+	after := newTestSigner(t, map[string]string{"old": "old-signing-key", "new": "new-signing-key"}, "new")
+	newToken, _, _ := after.Sign(uuid.New(), "customer")
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := after.Parse(token); err != nil {
+			t.Errorf("%s token after rotation: %v", name, err)
+		}
+	}
+
+	retired := newTestSigner(t, map[string]string{"new": "new-signing-key"}, "new")
+	if _, err := retired.Parse(oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old token once its key is removed: %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	if _, err := NewSigner(nil, "", time.Minute); err == nil {
+		t.Error("no keys: want an error")
+	}
+	if _, err := NewSigner(map[string]string{"a": "x", "b": "y"}, "", time.Minute); err == nil {
+		t.Error("two keys and no active one: want an error")
+	}
+	if _, err := NewSigner(map[string]string{"a": "x"}, "b", time.Minute); err == nil {
+		t.Error("unknown active key: want an error")
+	}
+}
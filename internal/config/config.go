@@ -1,28 +1,130 @@
+// Package config loads the server settings. Every setting has a default and
+// can be overridden, in increasing precedence, by a YAML or TOML file, an
+// environment variable and a command line flag.
+//
+// A setting is described by the tags of its Config field: config is the
+// dotted key used in the file, the flag name is the key with dots and
+// underscores turned into dashes, env names the environment variable and
+// secret hides the value when the config is printed.
 package config
 
 import (
-	"os"
+	"log/slog"
+	"time"
 )
 
-type Config struct{
-    
-	DatabaseDN string
-}
+type Config struct {
+	// DatabaseDriver is one of sqlite, postgres or mysql
+	DatabaseDriver string `config:"database.driver" env:"DATABASE_DRIVER" default:"sqlite"`
+
+	// DatabaseDN is the SQLite DSN, PostgresDSN and MySQLDSN are used when
+	// their driver is selected
+	DatabaseDN string `config:"database.sqlite_dsn" env:"DATABASE_DN" default:"homework1.db"`
+
+	PostgresDSN string `config:"database.postgres_dsn" env:"POSTGRES_DSN" secret:"true"`
+
+	MySQLDSN string `config:"database.mysql_dsn" env:"MYSQL_DSN" secret:"true"`
+
+	DBMaxOpenConns int `config:"database.max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"10"`
+
+	DBMaxIdleConns int `config:"database.max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"5"`
+
+	DBConnMaxLifetime time.Duration `config:"database.conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+
+	// AllowPendingMigrations lets the server start on an outdated schema
+	AllowPendingMigrations bool `config:"database.allow_pending_migrations" env:"ALLOW_PENDING_MIGRATIONS" default:"false"`
+
+	// JWTSigningKeys maps a key ID to its HMAC secret, every key is accepted
+	// when verifying so old tokens keep working while keys are rotated. The
+	// environment and flags take comma separated kid:secret pairs.
+	JWTSigningKeys map[string]string `config:"auth.signing_keys" env:"JWT_SIGNING_KEYS" secret:"true"`
+
+	// JWTActiveKeyID selects the key new tokens are signed with
+	JWTActiveKeyID string `config:"auth.active_key_id" env:"JWT_ACTIVE_KEY_ID"`
+
+	AccessTokenTTL time.Duration `config:"auth.access_token_ttl" env:"ACCESS_TOKEN_TTL" default:"15m"`
+
+	RefreshTokenTTL time.Duration `config:"auth.refresh_token_ttl" env:"REFRESH_TOKEN_TTL" default:"168h"`
+
+	// UserDeletePolicy is cascade, restrict or reassign and decides what
+	// happens to a deleted user's products
+	UserDeletePolicy string `config:"users.delete_policy" env:"USER_DELETE_POLICY" default:"restrict"`
+
+	// PurgeRetention is how long soft deleted rows are kept by default
+	PurgeRetention time.Duration `config:"purge.retention" env:"PURGE_RETENTION" default:"720h"`
+
+	// IdempotencyKeyTTL is how long the response to a request with an
+	// Idempotency-Key is kept for replay
+	IdempotencyKeyTTL time.Duration `config:"idempotency.key_ttl" env:"IDEMPOTENCY_KEY_TTL" default:"24h"`
+
+	// ImportSyncRows is the largest product import answered directly, larger
+	// ones run as background jobs. ImportMaxRows rejects anything bigger.
+	ImportSyncRows int `config:"import.sync_rows" env:"IMPORT_SYNC_ROWS" default:"1000"`
+
+	ImportMaxRows int `config:"import.max_rows" env:"IMPORT_MAX_ROWS" default:"100000"`
 
-// create function to load configuration
-func LoadConfig() *Config{
-	
-//    return configuration
-     return &Config{
-		DatabaseDN: getEnv("DATABASE_DN", "homework1.db"),
-	 }
+	// ImportMaxBodySize rejects larger import bodies before they are parsed
+	ImportMaxBodySize Size `config:"import.max_body_size" env:"IMPORT_MAX_BODY_SIZE" default:"64MiB"`
+
+	// ImportJobRetention is how long a finished import job can be looked up
+	ImportJobRetention time.Duration `config:"import.job_retention" env:"IMPORT_JOB_RETENTION" default:"24h"`
+
+	// HTTPAddr is the address the server listens on
+	HTTPAddr string `config:"http.addr" env:"HTTP_ADDR" default:":8080"`
+
+	// HTTPReadTimeout bounds reading a whole request, HTTPReadHeaderTimeout
+	// just its headers
+	HTTPReadTimeout time.Duration `config:"http.read_timeout" env:"HTTP_READ_TIMEOUT" default:"30s"`
+
+	HTTPReadHeaderTimeout time.Duration `config:"http.read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+
+	// HTTPWriteTimeout bounds writing a response, exports are exempt since
+	// they stream
+	HTTPWriteTimeout time.Duration `config:"http.write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"60s"`
+
+	HTTPIdleTimeout time.Duration `config:"http.idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+
+	HTTPMaxHeaderBytes Size `config:"http.max_header_size" env:"HTTP_MAX_HEADER_BYTES" default:"1MiB"`
+
+	// ShutdownTimeout is how long in-flight requests and import jobs get to
+	// finish after SIGTERM or SIGINT
+	ShutdownTimeout time.Duration `config:"http.shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s"`
+
+	// ShutdownDrainDelay is how long /readyz fails before the server stops
+	// accepting connections, giving load balancers time to notice
+	ShutdownDrainDelay time.Duration `config:"http.shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// HealthCheckTimeout bounds each readiness check
+	HealthCheckTimeout time.Duration `config:"health.check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+
+	// HealthMinFreeDisk fails readiness when the volume of the SQLite file
+	// has less space left
+	HealthMinFreeDisk Size `config:"health.min_free_disk" env:"HEALTH_MIN_FREE_DISK" default:"100MiB"`
+
+	// LogLevel is debug, info, warn or error, SQL statements are logged at
+	// debug
+	LogLevel slog.Level `config:"log.level" env:"LOG_LEVEL" default:"info"`
+
+	// SlowQueryThreshold logs slower queries as warnings, zero disables it
+	SlowQueryThreshold time.Duration `config:"log.slow_query_threshold" env:"SLOW_QUERY_THRESHOLD" default:"200ms"`
+
+	// BootstrapAdminEmail and BootstrapAdminPassword create the first admin
+	// on startup, sign up cannot hand out the admin role
+	BootstrapAdminEmail string `config:"bootstrap.admin_email" env:"BOOTSTRAP_ADMIN_EMAIL"`
+
+	BootstrapAdminPassword string `config:"bootstrap.admin_password" env:"BOOTSTRAP_ADMIN_PASSWORD" secret:"true"`
+
+	// sources records where each setting got its value, by key
+	sources map[string]string
 }
 
-// function calling env file to config database 
-func getEnv(key, defaultValue string) string {
-	value, ok := os.LookupEnv(key)
-	if !ok {
-		return defaultValue
+// DSN returns the connection string of the selected driver
+func (c *Config) DSN() string {
+	switch c.DatabaseDriver {
+	case "postgres":
+		return c.PostgresDSN
+	case "mysql":
+		return c.MySQLDSN
 	}
-	return value
-}
\ No newline at end of file
+	return c.DatabaseDN
+}
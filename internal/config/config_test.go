@@ -0,0 +1,319 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate clears every variable Load reads, an empty one counts as unset
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Setenv(FileEnv, "")
+	for _, s := range settings {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags
+}
+
+func TestDefaults(t *testing.T) {
+	isolate(t)
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DatabaseDriver != "sqlite" || c.DSN() != "homework1.db" || c.AccessTokenTTL != 15*time.Minute ||
+		c.ImportMaxBodySize != 64<<20 || c.HTTPAddr != ":8080" {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+	if c.sources["http.addr"] != "default" {
+		t.Errorf("http.addr source %q, want default", c.sources["http.addr"])
+	}
+}
+
+// TestPrecedence sets http.addr, log.level and import.sync_rows in more and
+// more layers, each layer winning over the ones before
+func TestPrecedence(t *testing.T) {
+	isolate(t)
+	file := writeFile(t, "config.yaml", `
+http:
+  addr: ":1000"
+log:
+  level: warn
+import:
+  sync_rows: 7
+`)
+	t.Setenv(FileEnv, file)
+	t.Setenv("HTTP_ADDR", ":2000")
+	t.Setenv("LOG_LEVEL", "error")
+
+	c, err := Load(parseFlags(t, "--log-level", "debug"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, got, want, source string
+	}{
+		{"import.sync_rows", strconv.Itoa(c.ImportSyncRows), "7", "file " + file},
+		{"http.addr", c.HTTPAddr, ":2000", "env HTTP_ADDR"},
+		{"log.level", c.LogLevel.String(), "DEBUG", "flag --log-level"},
+		{"import.max_rows", strconv.Itoa(c.ImportMaxRows), "100000", "default"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want || c.sources[tt.key] != tt.source {
+			t.Errorf("%s = %s from %s, want %s from %s", tt.key, tt.got, c.sources[tt.key], tt.want, tt.source)
+		}
+	}
+
+	// --config wins over CONFIG_FILE
+	other := writeFile(t, "other.toml", "[import]\nsync_rows = 9\n")
+	c, err = Load(parseFlags(t, "--config", other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ImportSyncRows != 9 {
+		t.Errorf("import.sync_rows = %d from %s, want 9 from %s", c.ImportSyncRows, c.sources["import.sync_rows"], other)
+	}
+}
+
+func TestFileFormats(t *testing.T) {
+	isolate(t)
+	files := map[string]string{
+		"config.yaml": `
+auth:
+  signing_keys:
+    old: old-secret
+    new: new-secret
+  active_key_id: new
+database:
+  allow_pending_migrations: true
+  conn_max_lifetime: 1h
+`,
+		"config.toml": `
+[auth]
+active_key_id = "new"
+[auth.signing_keys]
+old = "old-secret"
+new = "new-secret"
+[database]
+allow_pending_migrations = true
+conn_max_lifetime = "1h"
+`,
+	}
+	for name, content := range files {
+		c, err := Load(parseFlags(t, "--config", writeFile(t, name, content)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(c.JWTSigningKeys) != 2 || c.JWTSigningKeys["new"] != "new-secret" || c.JWTActiveKeyID != "new" {
+			t.Errorf("%s: signing keys %v active %q", name, c.JWTSigningKeys, c.JWTActiveKeyID)
+		}
+		if !c.AllowPendingMigrations || c.DBConnMaxLifetime != time.Hour {
+			t.Errorf("%s: allow_pending_migrations %v, conn_max_lifetime %v", name, c.AllowPendingMigrations, c.DBConnMaxLifetime)
+		}
+	}
+
+	t.Setenv("JWT_SIGNING_KEYS", "a:one, b:two")
+	t.Setenv("JWT_ACTIVE_KEY_ID", "b")
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.JWTSigningKeys) != 2 || c.JWTSigningKeys["a"] != "one" || c.JWTSigningKeys["b"] != "two" {
+		t.Errorf("signing keys from the environment %v", c.JWTSigningKeys)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+		err  bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"10B", 10, false},
+		{"1KB", 1000, false},
+		{"1KiB", 1024, false},
+		{" 64 MiB ", 64 << 20, false},
+		{"2GB", 2_000_000_000, false},
+		{"3GiB", 3 << 30, false},
+		{"8589934591GiB", 8589934591 << 30, false},
+		{"8589934592GiB", 0, true},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"1.5MiB", 0, true},
+		{"1TiB", 0, true},
+		{"MiB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+
+	for size, want := range map[Size]string{0: "0B", 1000: "1000B", 1024: "1KiB", 1536: "1536B", 64 << 20: "64MiB", 2 << 30: "2GiB"} {
+		if got := size.String(); got != want {
+			t.Errorf("Size(%d).String() = %s, want %s", int64(size), got, want)
+		}
+	}
+}
+
+// TestParseErrors checks that bad values are reported with their key and
+// source, all of them at once
+func TestParseErrors(t *testing.T) {
+	isolate(t)
+	t.Setenv("ACCESS_TOKEN_TTL", "15")
+	t.Setenv("IMPORT_MAX_BODY_SIZE", "lots")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("DB_MAX_OPEN_CONNS", "ten")
+	t.Setenv("ALLOW_PENDING_MIGRATIONS", "maybe")
+	t.Setenv("JWT_SIGNING_KEYS", "no-secret")
+
+	_, err := Load(parseFlags(t, "--http-read-timeout", "1 minute"))
+	if err == nil {
+		t.Fatal("Load accepted bad values")
+	}
+	for _, want := range []string{
+		`auth.access_token_ttl from env ACCESS_TOKEN_TTL: invalid duration "15"`,
+		`import.max_body_size from env IMPORT_MAX_BODY_SIZE: invalid size "lots"`,
+		`log.level from env LOG_LEVEL: invalid level "loud"`,
+		`database.max_open_conns from env DB_MAX_OPEN_CONNS: invalid integer "ten"`,
+		`database.allow_pending_migrations from env ALLOW_PENDING_MIGRATIONS`,
+		`auth.signing_keys from env JWT_SIGNING_KEYS`,
+		`http.read_timeout from flag --http-read-timeout: invalid duration "1 minute"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	isolate(t)
+	file := writeFile(t, "config.yaml", "http:\n  adress: \":1\"\n  addr: [\":1\"]\n")
+	_, err = Load(parseFlags(t, "--config", file))
+	if err == nil || !strings.Contains(err.Error(), "http.adress in "+file+": unknown setting") ||
+		!strings.Contains(err.Error(), "http.addr from file "+file+": expected a single value") {
+		t.Errorf("file errors: %v", err)
+	}
+	if _, err := Load(parseFlags(t, "--config", writeFile(t, "config.json", "{}"))); err == nil {
+		t.Error("Load read a .json file")
+	}
+	if _, err := Load(parseFlags(t, "--config", filepath.Join(t.TempDir(), "missing.yaml"))); err == nil {
+		t.Error("Load read a missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"DATABASE_DRIVER": "oracle"}, `database.driver from env DATABASE_DRIVER: "oracle" is not one of`},
+		{map[string]string{"DATABASE_DRIVER": "postgres"}, "database.postgres_dsn from default: must be set for the postgres driver"},
+		{map[string]string{"DB_MAX_IDLE_CONNS": "-1"}, "database.max_idle_conns from env DB_MAX_IDLE_CONNS: must not be negative"},
+		{map[string]string{"JWT_SIGNING_KEYS": "a:one", "JWT_ACTIVE_KEY_ID": "b"}, `auth.active_key_id from env JWT_ACTIVE_KEY_ID: "b" does not name one of auth.signing_keys`},
+		{map[string]string{"USER_DELETE_POLICY": "orphan"}, "users.delete_policy from env USER_DELETE_POLICY"},
+		{map[string]string{"IMPORT_SYNC_ROWS": "0"}, "import.sync_rows from env IMPORT_SYNC_ROWS: must be positive"},
+		{map[string]string{"IMPORT_MAX_ROWS": "10"}, "import.max_rows from env IMPORT_MAX_ROWS: must be at least import.sync_rows (1000)"},
+		{map[string]string{"IMPORT_MAX_BODY_SIZE": "0"}, "import.max_body_size from env IMPORT_MAX_BODY_SIZE: must be positive"},
+		{map[string]string{"BOOTSTRAP_ADMIN_EMAIL": "admin@x.io"}, "bootstrap.admin_password from default: must be set with bootstrap.admin_email"},
+		{map[string]string{"HTTP_MAX_HEADER_BYTES": "2GiB"}, "http.max_header_size from env HTTP_MAX_HEADER_BYTES: must be between 1B and 1GiB"},
+		{map[string]string{"ACCESS_TOKEN_TTL": "0s"}, "auth.access_token_ttl from env ACCESS_TOKEN_TTL: must be positive"},
+		{map[string]string{"PURGE_RETENTION": "-1h"}, "purge.retention from env PURGE_RETENTION: must be positive"},
+		{map[string]string{"HTTP_WRITE_TIMEOUT": "-1s"}, "http.write_timeout from env HTTP_WRITE_TIMEOUT: must not be negative"},
+	}
+	for _, tt := range tests {
+		isolate(t)
+		for key, value := range tt.env {
+			t.Setenv(key, value)
+		}
+		_, err := Load(nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: error %v, want %q", tt.env, err, tt.want)
+		}
+	}
+
+	// zero disables the timeouts that allow it
+	isolate(t)
+	t.Setenv("HTTP_WRITE_TIMEOUT", "0s")
+	t.Setenv("SLOW_QUERY_THRESHOLD", "0s")
+	if _, err := Load(nil); err != nil {
+		t.Errorf("zero timeouts: %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	isolate(t)
+	t.Setenv("DATABASE_DRIVER", "postgres")
+	t.Setenv("POSTGRES_DSN", "postgres://app:hunter2@db/app")
+	t.Setenv("JWT_SIGNING_KEYS", "2024:first-secret,2025:second-secret")
+	t.Setenv("JWT_ACTIVE_KEY_ID", "2025")
+	t.Setenv("BOOTSTRAP_ADMIN_EMAIL", "admin@x.io")
+	t.Setenv("BOOTSTRAP_ADMIN_PASSWORD", "admin-password")
+	c, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := c.Print(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, secret := range []string{"hunter2", "first-secret", "second-secret", "admin-password"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed config shows %q:\n%s", secret, out)
+		}
+	}
+
+	lines := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		lines[fields[0]] = fields[1:]
+	}
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{"database.postgres_dsn", redacted},
+		{"database.mysql_dsn", `""`},
+		{"auth.signing_keys", "2024:" + redacted + ",2025:" + redacted},
+		{"auth.active_key_id", "2025"},
+		{"bootstrap.admin_password", redacted},
+		{"bootstrap.admin_email", "admin@x.io"},
+		{"import.max_body_size", "64MiB"},
+	}
+	for _, tt := range tests {
+		if got := lines[tt.key]; len(got) == 0 || got[0] != tt.value {
+			t.Errorf("%s printed as %v, want %s", tt.key, got, tt.value)
+		}
+	}
+	if got := lines["database.postgres_dsn"]; len(got) < 3 || strings.Join(got[1:], " ") != "env POSTGRES_DSN" {
+		t.Errorf("database.postgres_dsn source %v, want env POSTGRES_DSN", got)
+	}
+}
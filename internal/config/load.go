@@ -0,0 +1,324 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the config file when --config is not given
+const FileEnv = "CONFIG_FILE"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	sizeType     = reflect.TypeOf(Size(0))
+	keysType     = reflect.TypeOf(map[string]string(nil))
+	levelType    = reflect.TypeOf(slog.Level(0))
+)
+
+// setting is one tagged field of Config
+type setting struct {
+	key, env, flag, def string
+	secret              bool
+	index               int
+}
+
+var settings = func() []setting {
+	t := reflect.TypeOf(Config{})
+	var all []setting
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := f.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+		all = append(all, setting{
+			key:    key,
+			env:    f.Tag.Get("env"),
+			flag:   strings.NewReplacer(".", "-", "_", "-").Replace(key),
+			def:    f.Tag.Get("default"),
+			secret: f.Tag.Get("secret") == "true",
+			index:  i,
+		})
+	}
+	return all
+}()
+
+// Flags holds the command line flags of every setting plus --config
+type Flags struct {
+	fs     *flag.FlagSet
+	file   string
+	byFlag map[string]setting
+}
+
+// RegisterFlags adds the flags to fs, Load reads the ones set once fs is
+// parsed
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, byFlag: make(map[string]setting, len(settings))}
+	fs.StringVar(&f.file, "config", "", "YAML or TOML config file, also read from "+FileEnv)
+	for _, s := range settings {
+		ft := reflect.TypeOf(Config{}).Field(s.index).Type
+		usage := "sets " + s.key + " (`" + typeName(ft) + "`)"
+		if s.env != "" {
+			usage += ", also read from " + s.env
+		}
+		fs.Var(&flagValue{isBool: ft.Kind() == reflect.Bool}, s.flag, usage)
+		f.byFlag[s.flag] = s
+	}
+	return f
+}
+
+// flagValue keeps the raw text, it is parsed with the other layers
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string     { return v.value }
+func (v *flagValue) Set(s string) error { v.value = s; return nil }
+func (v *flagValue) IsBoolFlag() bool   { return v.isBool }
+
+// Load merges the defaults, the config file, the environment and the flags
+// set in flags, which may be nil, in that order, then validates the result.
+// The error lists every problem found.
+func Load(flags *Flags) (*Config, error) {
+	c := &Config{sources: make(map[string]string, len(settings))}
+	v := reflect.ValueOf(c).Elem()
+	var errs []error
+	set := func(s setting, raw any, source string) {
+		if err := assign(v.Field(s.index), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s from %s: %w", s.key, source, err))
+			return
+		}
+		c.sources[s.key] = source
+	}
+
+	for _, s := range settings {
+		if err := assign(v.Field(s.index), s.def); err != nil {
+			panic(fmt.Sprintf("config: bad default for %s: %v", s.key, err))
+		}
+		c.sources[s.key] = "default"
+	}
+
+	file := os.Getenv(FileEnv)
+	if flags != nil && flags.file != "" {
+		file = flags.file
+	}
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		byKey := make(map[string]setting, len(settings))
+		for _, s := range settings {
+			byKey[s.key] = s
+		}
+		walk("", values, func(key string, value any) {
+			s, ok := byKey[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s in %s: unknown setting", key, file))
+				return
+			}
+			set(s, value, "file "+file)
+		})
+	}
+
+	// empty variables count as unset, as compose files often leave them
+	for _, s := range settings {
+		if value := os.Getenv(s.env); s.env != "" && value != "" {
+			set(s, value, "env "+s.env)
+		}
+	}
+
+	if flags != nil {
+		flags.fs.Visit(func(f *flag.Flag) {
+			if s, ok := flags.byFlag[f.Name]; ok {
+				set(s, f.Value.String(), "flag --"+f.Name)
+			}
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile decodes a YAML or TOML file, told apart by its extension
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// walk calls fn with the dotted key of every value in the nested tables,
+// a table standing for a map setting such as auth.signing_keys is passed
+// whole
+func walk(prefix string, values map[string]any, fn func(key string, value any)) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+		if table, ok := values[key].(map[string]any); ok && !isMapSetting(full) {
+			walk(full, table, fn)
+			continue
+		}
+		fn(full, values[key])
+	}
+}
+
+func isMapSetting(key string) bool {
+	for _, s := range settings {
+		if s.key == key {
+			return reflect.TypeOf(Config{}).Field(s.index).Type == keysType
+		}
+	}
+	return false
+}
+
+// assign parses raw into field. raw is text from a default, the environment
+// or a flag, or a decoded file value.
+func assign(field reflect.Value, raw any) error {
+	if field.Type() == keysType {
+		keys, err := parseKeys(raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(keys))
+		return nil
+	}
+
+	var text string
+	switch raw := raw.(type) {
+	case string:
+		text = raw
+	case bool, int, int64, uint64, float64:
+		text = fmt.Sprint(raw)
+	default:
+		return fmt.Errorf("expected a single value, got %T", raw)
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a unit such as 30s or 24h", text)
+		}
+		field.SetInt(int64(d))
+		return nil
+	case sizeType:
+		size, err := ParseSize(text)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(size))
+		return nil
+	case levelType:
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("invalid level %q, use debug, info, warn or error", text)
+		}
+		field.SetInt(int64(level))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// parseKeys reads signing keys from a table of kid to secret, or from a
+// comma separated list of kid:secret pairs
+func parseKeys(raw any) (map[string]string, error) {
+	keys := map[string]string{}
+	switch raw := raw.(type) {
+	case map[string]any:
+		for kid, secret := range raw {
+			s, ok := secret.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("secret of key %q must be a non-empty string", kid)
+			}
+			keys[kid] = s
+		}
+	case string:
+		for _, pair := range strings.Split(raw, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			kid, secret, ok := strings.Cut(pair, ":")
+			if !ok || kid == "" || secret == "" {
+				return nil, errors.New("expected comma separated kid:secret pairs")
+			}
+			keys[kid] = secret
+		}
+	default:
+		return nil, fmt.Errorf("expected a table or kid:secret pairs, got %T", raw)
+	}
+	return keys, nil
+}
+
+// typeName names the value a flag takes in its usage
+func typeName(t reflect.Type) string {
+	switch t {
+	case durationType:
+		return "duration"
+	case sizeType:
+		return "size"
+	case keysType:
+		return "kid:secret,..."
+	case levelType:
+		return "level"
+	}
+	return t.Kind().String()
+}
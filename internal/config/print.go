@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const redacted = "[redacted]"
+
+// Print writes every setting with its effective value and where the value
+// came from. Secrets are redacted, signing keys keep their IDs.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	v := reflect.ValueOf(c).Elem()
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, format(v.Field(s.index), s.secret), c.sources[s.key])
+	}
+	return tw.Flush()
+}
+
+func format(field reflect.Value, secret bool) string {
+	if keys, ok := field.Interface().(map[string]string); ok {
+		pairs := make([]string, 0, len(keys))
+		for kid, value := range keys {
+			if secret {
+				value = redacted
+			}
+			pairs = append(pairs, kid+":"+value)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+	if secret && !field.IsZero() {
+		return redacted
+	}
+	if s, ok := field.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if field.Kind() == reflect.String && field.String() == "" {
+		return `""`
+	}
+	return fmt.Sprint(field.Interface())
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	drivers        = []string{"sqlite", "postgres", "mysql"}
+	deletePolicies = []string{"cascade", "restrict", "reassign"}
+)
+
+// validate checks the settings against each other, naming the source of
+// every offending value
+func (c *Config) validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s from %s: %s", key, c.sources[key], fmt.Sprintf(format, args...)))
+	}
+
+	if !slices.Contains(drivers, c.DatabaseDriver) {
+		fail("database.driver", "%q is not one of %v", c.DatabaseDriver, drivers)
+	} else if c.DSN() == "" {
+		fail("database."+c.DatabaseDriver+"_dsn", "must be set for the %s driver", c.DatabaseDriver)
+	}
+	if c.DBMaxOpenConns < 0 {
+		fail("database.max_open_conns", "must not be negative")
+	}
+	if c.DBMaxIdleConns < 0 {
+		fail("database.max_idle_conns", "must not be negative")
+	}
+
+	if len(c.JWTSigningKeys) > 0 {
+		if _, ok := c.JWTSigningKeys[c.JWTActiveKeyID]; !ok {
+			fail("auth.active_key_id", "%q does not name one of auth.signing_keys", c.JWTActiveKeyID)
+		}
+	}
+	if !slices.Contains(deletePolicies, c.UserDeletePolicy) {
+		fail("users.delete_policy", "%q is not one of %v", c.UserDeletePolicy, deletePolicies)
+	}
+
+	if c.ImportSyncRows <= 0 {
+		fail("import.sync_rows", "must be positive")
+	}
+	if c.ImportMaxRows < c.ImportSyncRows {
+		fail("import.max_rows", "must be at least import.sync_rows (%d)", c.ImportSyncRows)
+	}
+	if c.ImportMaxBodySize <= 0 {
+		fail("import.max_body_size", "must be positive")
+	}
+	if c.BootstrapAdminEmail != "" && c.BootstrapAdminPassword == "" {
+		fail("bootstrap.admin_password", "must be set with bootstrap.admin_email")
+	}
+	if c.HTTPMaxHeaderBytes <= 0 || c.HTTPMaxHeaderBytes > 1<<30 {
+		fail("http.max_header_size", "must be between 1B and 1GiB")
+	}
+
+	positive := map[string]time.Duration{
+		"auth.access_token_ttl":  c.AccessTokenTTL,
+		"auth.refresh_token_ttl": c.RefreshTokenTTL,
+		"purge.retention":        c.PurgeRetention,
+		"idempotency.key_ttl":    c.IdempotencyKeyTTL,
+		"import.job_retention":   c.ImportJobRetention,
+		"http.shutdown_timeout":  c.ShutdownTimeout,
+		"health.check_timeout":   c.HealthCheckTimeout,
+	}
+	// zero disables these
+	notNegative := map[string]time.Duration{
+		"database.conn_max_lifetime": c.DBConnMaxLifetime,
+		"log.slow_query_threshold":   c.SlowQueryThreshold,
+		"http.read_timeout":          c.HTTPReadTimeout,
+		"http.read_header_timeout":   c.HTTPReadHeaderTimeout,
+		"http.write_timeout":         c.HTTPWriteTimeout,
+		"http.idle_timeout":          c.HTTPIdleTimeout,
+		"http.shutdown_drain_delay":  c.ShutdownDrainDelay,
+	}
+	for _, s := range settings {
+		if d, ok := positive[s.key]; ok && d <= 0 {
+			fail(s.key, "must be positive")
+		}
+		if d, ok := notNegative[s.key]; ok && d < 0 {
+			fail(s.key, "must not be negative")
+		}
+	}
+
+	return errors.Join(errs...)
+}
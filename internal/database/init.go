@@ -1,25 +1,117 @@
 package database
 
 import (
-	"homework1/internal/models"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"homework1/internal/audit"
+	"homework1/internal/config"
+	"homework1/internal/logging"
+	"homework1/internal/migrations"
 )
 
+// Dialector returns the GORM dialector for the configured driver. The
+// driver name doubles as the name of its migration set.
+func Dialector(driver, dsn string) (gorm.Dialector, error) {
+    switch driver {
+    case "sqlite":
+        return sqlite.Open(dsn), nil
+    case "postgres":
+        return postgres.Open(dsn), nil
+    case "mysql":
+        // migrations run several statements per Exec and time columns
+        // must scan into time.Time
+        cfg, err := gomysql.ParseDSN(dsn)
+        if err != nil {
+            return nil, err
+        }
+        cfg.MultiStatements = true
+        cfg.ParseTime = true
+        return mysql.Open(cfg.FormatDSN()), nil
+    }
+    return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// SQLiteFile returns the path of the database file named by a SQLite DSN,
+// in-memory databases have none
+func SQLiteFile(dsn string) (string, bool) {
+    path, query, _ := strings.Cut(dsn, "?")
+    path = strings.TrimPrefix(path, "file:")
+    if path == "" || path == ":memory:" || strings.Contains(query, "mode=memory") {
+        return "", false
+    }
+    return path, true
+}
+
+func InitDB(config *config.Config) *gorm.DB {
 
-func InitDB(dsn string) *gorm.DB {
+    dialector, err := Dialector(config.DatabaseDriver, config.DSN())
+    if err != nil {
+        logging.Fatal("failed to configure database", "error", err)
+    }
 
-    db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+    // timestamps are kept in UTC so they compare correctly where the
+    // database stores them as text. TranslateError turns driver specific
+    // constraint violations into gorm.ErrDuplicatedKey and friends. Queries are
+    // logged through slog with the request attributes of their context.
+    db, err := gorm.Open(dialector, &gorm.Config{
+        NowFunc: func() time.Time { return time.Now().UTC() },
+        TranslateError: true,
+        Logger: logging.NewGormLogger(slog.Default(), config.SlowQueryThreshold),
+    })
     if err != nil {
-        log.Fatalf("failed to connect to database: %v", err)
+        logging.Fatal("failed to connect to database", "error", err)
     }
 
-    // Perform automatic migration
-	err = db.AutoMigrate(&model.Product{}, &model.User{})
+    sqlDB, err := db.DB()
     if err != nil {
-        log.Fatalf("failed to migrate database: %v", err)
+        logging.Fatal("failed to get database handle", "error", err)
+    }
+    sqlDB.SetMaxOpenConns(config.DBMaxOpenConns)
+    sqlDB.SetMaxIdleConns(config.DBMaxIdleConns)
+    sqlDB.SetConnMaxLifetime(config.DBConnMaxLifetime)
+
+    if err := audit.RegisterCallbacks(db); err != nil {
+        logging.Fatal("failed to register audit callbacks", "error", err)
     }
 
     return db;
-}
\ No newline at end of file
+}
+
+// RequireSchema refuses to continue when migrations are pending, unless
+// allowPending is set, or when applied migrations do not match this build
+func RequireSchema(db *gorm.DB, dialect string, allowPending bool) {
+    migrator, err := migrations.New(db, dialect)
+    if err != nil {
+        logging.Fatal("failed to load migrations", "error", err)
+    }
+
+    err = migrator.Check()
+    if errors.Is(err, migrations.ErrSchemaBehind) {
+        if allowPending {
+            slog.Warn("continuing with pending migrations since they are allowed", "error", err)
+            return
+        }
+        logging.Fatal("refusing to start, run `go run ./cmd/migrate up`", "error", err)
+    }
+    if err != nil {
+        logging.Fatal("refusing to start", "error", err)
+    }
+}
+
+// Close closes the connection pool behind db
+func Close(db *gorm.DB) error {
+    sqlDB, err := db.DB()
+    if err != nil {
+        return err
+    }
+    return sqlDB.Close()
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"homework1/internal/models"
+)
+
+var testDSNs = map[string]string{
+	"sqlite":   "file::memory:",
+	"postgres": "host=localhost user=app password=secret dbname=app sslmode=disable",
+	"mysql":    "app:secret@tcp(localhost:3306)/app",
+}
+
+func TestDialector(t *testing.T) {
+	for driver, dsn := range testDSNs {
+		dialector, err := Dialector(driver, dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if dialector.Name() != driver {
+			t.Errorf("%s: got dialector %s", driver, dialector.Name())
+		}
+	}
+
+	dialector, _ := Dialector("mysql", testDSNs["mysql"])
+	dsn := dialector.(*mysql.Dialector).Config.DSN
+	for _, option := range []string{"multiStatements=true", "parseTime=true"} {
+		if !strings.Contains(dsn, option) {
+			t.Errorf("mysql DSN %q lacks %s", dsn, option)
+		}
+	}
+
+	if _, err := Dialector("oracle", "x"); err == nil {
+		t.Error("oracle: want an error")
+	}
+	if _, err := Dialector("mysql", "not a dsn"); err == nil {
+		t.Error("invalid mysql DSN: want an error")
+	}
+}
+
+// dryRun opens driver without a server, statements are built but not run
+func dryRun(t *testing.T, driver string) *gorm.DB {
+	t.Helper()
+	dialector, err := Dialector(driver, testDSNs[driver])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := dialector.(*mysql.Dialector); ok {
+		d.Config.SkipInitializeWithVersion = true
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("%s: %v", driver, err)
+	}
+	return db
+}
+
+var uuidTypes = map[reflect.Type]bool{
+	reflect.TypeOf(uuid.UUID{}):  true,
+	reflect.TypeOf(&uuid.UUID{}): true,
+}
+
+// TestUUIDColumnsArePortable checks that UUIDs are char(36) on every driver,
+// postgres would otherwise need its uuid type, and that they are sent as
+// their text form
+func TestUUIDColumnsArePortable(t *testing.T) {
+	models := []any{
+		&model.User{}, &model.Product{}, &model.RefreshToken{}, &model.IdempotencyKey{},
+		&model.RolePermission{}, &model.AccessDenial{}, &model.HistoryEntry{},
+	}
+	for driver := range testDSNs {
+		db := dryRun(t, driver)
+		for _, m := range models {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(m); err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+			for _, field := range stmt.Schema.Fields {
+				if !uuidTypes[field.FieldType] {
+					continue
+				}
+				if got := db.Migrator().FullDataTypeOf(field).SQL; !strings.HasPrefix(got, "char(36)") {
+					t.Errorf("%s: %s.%s is %q, want char(36)", driver, stmt.Schema.Name, field.Name, got)
+				}
+			}
+		}
+
+		id, owner := uuid.New(), uuid.New()
+		insert := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Create(&model.Product{ID: id, UserID: owner, Name: "Chair", Price: model.NewMoney(100, "USD")})
+		})
+		query := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("user_id = ?", owner).Find(&[]model.Product{})
+		})
+		for _, want := range []uuid.UUID{id, owner} {
+			if !quoted(insert, want.String()) {
+				t.Errorf("%s: insert %q lacks %s as text", driver, insert, want)
+			}
+		}
+		if !quoted(query, owner.String()) {
+			t.Errorf("%s: query %q lacks %s as text", driver, query, owner)
+		}
+	}
+}
+
+// quoted tells whether sql holds s as a string literal, SQLite explains
+// with double quotes
+func quoted(sql, s string) bool {
+	return strings.Contains(sql, "'"+s+"'") || strings.Contains(sql, `"`+s+`"`)
+}
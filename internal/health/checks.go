@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"homework1/internal/migrations"
+)
+
+// Database pings the connection pool
+func Database(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Migrations fails when the applied migrations do not match this build.
+// Pending migrations pass when allowPending is set, as they do at startup.
+// It only reads schema_migrations.
+func Migrations(db *gorm.DB, dialect string, allowPending bool) Check {
+	return func(ctx context.Context) error {
+		migrator, err := migrations.New(db.WithContext(ctx), dialect)
+		if err != nil {
+			return err
+		}
+		err = migrator.Check()
+		if allowPending && errors.Is(err, migrations.ErrSchemaBehind) {
+			return nil
+		}
+		return err
+	}
+}
+
+// DiskSpace fails when the volume holding dir has less than min bytes
+// available to the server
+func DiskSpace(dir string, min uint64) Check {
+	return func(context.Context) error {
+		free, err := freeBytes(dir)
+		if err != nil {
+			return err
+		}
+		if free < min {
+			return fmt.Errorf("%d bytes free in %s, the minimum is %d", free, dir, min)
+		}
+		return nil
+	}
+}
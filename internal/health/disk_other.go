@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package health
+
+import (
+	"errors"
+	"runtime"
+)
+
+func freeBytes(string) (uint64, error) {
+	return 0, errors.New("free disk space is not available on " + runtime.GOOS)
+}
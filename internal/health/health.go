@@ -0,0 +1,118 @@
+// Package health answers the liveness and readiness probes of an
+// orchestrator. Readiness runs named checks against the dependencies of the
+// server and fails once shutdown has begun.
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"homework1/internal/logging"
+)
+
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+// ErrShuttingDown fails readiness while the server drains
+var ErrShuttingDown = errors.New("the server is shutting down")
+
+// Failed checks report one of these, their causes may name hosts, paths or
+// SQL and are logged instead
+const (
+	failedMessage   = "check failed, see the server log"
+	timedOutMessage = "check timed out"
+)
+
+// Check returns nil when its dependency is usable
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks
+type Checker struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker bounds every check by timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check, checks are added before serving
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name, check})
+}
+
+// Drain makes readiness fail from now on
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Readiness is the outcome of the readiness checks
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Ready runs the checks concurrently. Once draining only the shutdown is
+// reported, the other checks are skipped.
+func (c *Checker) Ready(ctx context.Context) Readiness {
+	if c.draining.Load() {
+		return Readiness{Status: StatusFail, Checks: map[string]CheckResult{
+			"shutdown": {Status: StatusFail, Error: ErrShuttingDown.Error()},
+		}}
+	}
+
+	report := Readiness{Status: StatusPass, Checks: make(map[string]CheckResult, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx, nc.name, nc.check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status == StatusFail {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, name string, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: StatusPass, DurationMs: logging.Milliseconds(time.Since(start))}
+	if err != nil {
+		slog.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+		result.Status = StatusFail
+		result.Error = failedMessage
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.Error = timedOutMessage
+		}
+	}
+	return result
+}
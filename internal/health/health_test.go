@@ -0,0 +1,140 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"homework1/internal/migrations"
+)
+
+// captureLog sends the default logger to a buffer for the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestReady(t *testing.T) {
+	log := captureLog(t)
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("ok", func(context.Context) error { return nil })
+	report := checker.Ready(context.Background())
+	if report.Status != StatusPass || report.Checks["ok"].Status != StatusPass || report.Checks["ok"].Error != "" {
+		t.Errorf("passing checks reported %+v", report)
+	}
+
+	checker.Add("broken", func(context.Context) error {
+		return errors.New("dial tcp db.internal:5432: connection refused")
+	})
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report = checker.Ready(context.Background())
+	if report.Status != StatusFail || report.Checks["ok"].Status != StatusPass {
+		t.Errorf("failing checks reported %+v", report)
+	}
+	if got := report.Checks["broken"]; got.Status != StatusFail || got.Error != failedMessage {
+		t.Errorf("broken check reported %+v, want %q", got, failedMessage)
+	}
+	if got := report.Checks["slow"]; got.Status != StatusFail || got.Error != timedOutMessage {
+		t.Errorf("slow check reported %+v, want %q", got, timedOutMessage)
+	}
+	if !strings.Contains(log.String(), "check=broken") || !strings.Contains(log.String(), "db.internal:5432") {
+		t.Errorf("the cause of the failure is not logged:\n%s", log)
+	}
+
+	checker.Drain()
+	report = checker.Ready(context.Background())
+	if report.Status != StatusFail || len(report.Checks) != 1 || report.Checks["shutdown"].Error != ErrShuttingDown.Error() {
+		t.Errorf("draining checker reported %+v", report)
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := freeBytes(dir); err != nil {
+		t.Skip(err)
+	}
+	if err := DiskSpace(dir, 0)(context.Background()); err != nil {
+		t.Errorf("no minimum: %v", err)
+	}
+	if err := DiskSpace(dir, 1<<62)(context.Background()); err == nil {
+		t.Error("an impossible minimum passes")
+	}
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	if err := Migrations(db, "sqlite", false)(ctx); !errors.Is(err, migrations.ErrSchemaBehind) {
+		t.Errorf("empty database: %v, want ErrSchemaBehind", err)
+	}
+	if err := Migrations(db, "sqlite", true)(ctx); err != nil {
+		t.Errorf("empty database with pending migrations allowed: %v", err)
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Fatal("the check created schema_migrations")
+	}
+
+	migrator, err := migrations.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrations(db, "sqlite", false)(ctx); !errors.Is(err, migrations.ErrSchemaBehind) {
+		t.Errorf("partly migrated database: %v, want ErrSchemaBehind", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrations(db, "sqlite", false)(ctx); err != nil {
+		t.Errorf("migrated database: %v", err)
+	}
+
+	// a migration this build does not know fails even when pending ones pass
+	if err := db.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (9999, 'future', 'x', CURRENT_TIMESTAMP)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrations(db, "sqlite", true)(ctx); !errors.Is(err, migrations.ErrUnknownVersion) {
+		t.Errorf("database from a newer build: %v, want ErrUnknownVersion", err)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if err := Migrations(db, "sqlite", true)(ctx); err == nil {
+		t.Error("closed database passes")
+	}
+	if err := Database(db)(ctx); err == nil {
+		t.Error("closed database pings")
+	}
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// records decodes the JSON lines written by a logger
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var all []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		all = append(all, record)
+	}
+	return all
+}
+
+func TestContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	ctx := With(context.Background(), slog.String("request_id", "r1"))
+	ctx = With(ctx, slog.String("user_id", "u1"))
+
+	logger.InfoContext(ctx, "request", "status", 200)
+	logger.With("component", "test").InfoContext(ctx, "grouped")
+	logger.InfoContext(context.Background(), "bare")
+	logger.DebugContext(ctx, "hidden")
+
+	got := records(t, &buf)
+	if len(got) != 3 {
+		t.Fatalf("%d records, want 3:\n%s", len(got), buf.String())
+	}
+	for _, r := range got[:2] {
+		if r["request_id"] != "r1" || r["user_id"] != "u1" {
+			t.Errorf("record %v lacks the context attributes", r)
+		}
+	}
+	if got[1]["component"] != "test" {
+		t.Errorf("record %v lacks the logger attributes", got[1])
+	}
+	if _, ok := got[2]["request_id"]; ok {
+		t.Errorf("record %v logged without the context has its attributes", got[2])
+	}
+}
+
+func TestRedactsSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	ctx := With(context.Background(), slog.String("Authorization", "Bearer abc.def.ghi"))
+
+	logger.With("postgres_dsn", "postgres://app:hunter2@db/app").InfoContext(ctx, "login",
+		"email", "jane@example.com",
+		"password", "correct horse",
+		"refresh_token", "rt-secret",
+		"Cookie", "session=s3cret",
+		slog.Group("auth", slog.String("signing_secret", "key-secret"), slog.String("kid", "2025")),
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"abc.def.ghi", "hunter2", "correct horse", "rt-secret", "s3cret", "key-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log shows %q:\n%s", secret, out)
+		}
+	}
+	r := records(t, &buf)[0]
+	for _, key := range []string{"Authorization", "postgres_dsn", "password", "refresh_token", "Cookie"} {
+		if r[key] != Redacted {
+			t.Errorf("%s logged as %v, want %s", key, r[key], Redacted)
+		}
+	}
+	if r["email"] != "jane@example.com" || r["msg"] != "login" {
+		t.Errorf("record %v lost attributes that are not sensitive", r)
+	}
+	if auth, _ := r["auth"].(map[string]any); auth["signing_secret"] != Redacted || auth["kid"] != "2025" {
+		t.Errorf("group logged as %v", r["auth"])
+	}
+}
+
+type secretRow struct {
+	ID           int
+	PasswordHash string
+}
+
+func TestGormLogger(t *testing.T) {
+	var buf bytes.Buffer
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: NewGormLogger(New(&buf, slog.LevelDebug), time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&secretRow{}); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := db.Create(&secretRow{ID: 1, PasswordHash: "$argon2id$secret-hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var row secretRow
+	if err := db.First(&row, "password_hash = ?", "$argon2id$secret-hash").Error; err != nil {
+		t.Fatal(err)
+	}
+	_ = db.First(&row, 2).Error
+	db.Exec("SELECT * FROM missing_table WHERE password_hash = ?", "$argon2id$secret-hash")
+
+	if strings.Contains(buf.String(), "secret-hash") {
+		t.Errorf("query log shows a statement value:\n%s", buf.String())
+	}
+	got := records(t, &buf)
+	want := []struct{ level, msg string }{
+		{"DEBUG", "query"},
+		{"DEBUG", "query"},
+		{"DEBUG", "query"},
+		{"ERROR", "query failed"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d records, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i, w := range want {
+		if got[i]["level"] != w.level || got[i]["msg"] != w.msg {
+			t.Errorf("record %d is %v %v, want %s %s", i, got[i]["level"], got[i]["msg"], w.level, w.msg)
+		}
+	}
+	if sql, _ := got[1]["sql"].(string); !strings.Contains(sql, "password_hash = ?") {
+		t.Errorf("logged statement %q, want it with its placeholder", sql)
+	}
+	if _, ok := got[3]["error"]; !ok {
+		t.Errorf("failed statement logged without its error: %v", got[3])
+	}
+
+	// slower statements than the threshold are warnings
+	buf.Reset()
+	slow := NewGormLogger(New(&buf, slog.LevelWarn), time.Millisecond)
+	slow.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	slow.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 2", 1 }, nil)
+	slow.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 3", 0 }, gorm.ErrRecordNotFound)
+	slow.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 4", 0 }, errors.New("disk I/O error"))
+	got = records(t, &buf)
+	if len(got) != 2 || got[0]["msg"] != "slow query" || got[0]["sql"] != "SELECT 1" || got[1]["msg"] != "query failed" {
+		t.Errorf("records %v, want the slow query and the failed one", got)
+	}
+}
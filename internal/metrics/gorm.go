@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// RegisterDB times every statement run through db with callbacks and
+// reports the connection pool statistics of database/sql
+func RegisterDB(reg *Registry, db *gorm.DB) error {
+	duration := reg.NewHistogramVec("db_query_duration_seconds", "Duration of database statements.", DefaultBuckets, "operation", "table")
+	failures := reg.NewCounterVec("db_query_errors_total", "Database statements that failed, missing rows aside.", "operation", "table")
+
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(startKey, time.Now())
+	}
+	after := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			start, ok := tx.InstanceGet(startKey)
+			if !ok {
+				return
+			}
+			table := tx.Statement.Table
+			duration.Observe(time.Since(start.(time.Time)).Seconds(), operation, table)
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				failures.Inc(operation, table)
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	if err := errors.Join(
+		callbacks.Create().Before("*").Register("metrics:before_create", before),
+		callbacks.Create().After("*").Register("metrics:after_create", after("create")),
+		callbacks.Query().Before("*").Register("metrics:before_query", before),
+		callbacks.Query().After("*").Register("metrics:after_query", after("query")),
+		callbacks.Update().Before("*").Register("metrics:before_update", before),
+		callbacks.Update().After("*").Register("metrics:after_update", after("update")),
+		callbacks.Delete().Before("*").Register("metrics:before_delete", before),
+		callbacks.Delete().After("*").Register("metrics:after_delete", after("delete")),
+		callbacks.Row().Before("*").Register("metrics:before_row", before),
+		callbacks.Row().After("*").Register("metrics:after_row", after("row")),
+		callbacks.Raw().Before("*").Register("metrics:before_raw", before),
+		callbacks.Raw().After("*").Register("metrics:after_raw", after("raw")),
+	); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	reg.NewGaugeFunc("db_connections", "Open database connections by state.", []string{"state"}, func(_ context.Context, report Report) error {
+		stats := sqlDB.Stats()
+		report(float64(stats.InUse), "in_use")
+		report(float64(stats.Idle), "idle")
+		return nil
+	})
+	reg.NewGaugeFunc("db_max_open_connections", "Limit of open database connections, 0 is unlimited.", nil, func(_ context.Context, report Report) error {
+		report(float64(sqlDB.Stats().MaxOpenConnections))
+		return nil
+	})
+	reg.NewCounterFunc("db_waits_total", "Times a statement waited for a free connection.", nil, func(_ context.Context, report Report) error {
+		report(float64(sqlDB.Stats().WaitCount))
+		return nil
+	})
+	reg.NewCounterFunc("db_wait_seconds_total", "Time spent waiting for a free connection.", nil, func(_ context.Context, report Report) error {
+		report(sqlDB.Stats().WaitDuration.Seconds())
+		return nil
+	})
+	reg.NewCounterFunc("db_connections_closed_total", "Connections closed by the pool limits.", []string{"reason"}, func(_ context.Context, report Report) error {
+		stats := sqlDB.Stats()
+		report(float64(stats.MaxIdleClosed), "max_idle")
+		report(float64(stats.MaxIdleTimeClosed), "max_idle_time")
+		report(float64(stats.MaxLifetimeClosed), "max_lifetime")
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// HTTP holds the request metrics, routes are labelled by their template so
+// that IDs do not multiply the series
+type HTTP struct {
+	requests *CounterVec
+	duration *HistogramVec
+	inFlight *GaugeVec
+}
+
+func NewHTTP(reg *Registry) *HTTP {
+	return &HTTP{
+		requests: reg.NewCounterVec("http_requests_total", "HTTP requests served, by status code.", "method", "route", "status"),
+		duration: reg.NewHistogramVec("http_request_duration_seconds", "Time to serve HTTP requests.", DefaultBuckets, "method", "route"),
+		inFlight: reg.NewGaugeVec("http_requests_in_flight", "HTTP requests being served."),
+	}
+}
+
+// Init makes the latency of a route show up before it is first requested
+func (h *HTTP) Init(method, route string) {
+	h.duration.Init(method, route)
+}
+
+// Begin counts a request in flight until End
+func (h *HTTP) Begin() {
+	h.inFlight.Inc()
+}
+
+func (h *HTTP) End(method, route string, status int, elapsed time.Duration) {
+	h.inFlight.Dec()
+	h.requests.Inc(method, route, strconv.Itoa(status))
+	h.duration.Observe(elapsed.Seconds(), method, route)
+}
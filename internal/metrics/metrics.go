@@ -0,0 +1,347 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format, version 0.0.4.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+type metric interface {
+	// write sends the samples, collected metrics may fail
+	write(ctx context.Context, w *bufio.Writer) error
+}
+
+// Registry holds the metrics of the process
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register panics on invalid or duplicate names, both are programming
+// errors
+func (r *Registry) register(name string, labels []string, m metric) {
+	if !validName.MatchString(name) {
+		panic("metrics: invalid metric name " + name)
+	}
+	for _, label := range labels {
+		if !validName.MatchString(label) || strings.Contains(label, ":") || strings.HasPrefix(label, "__") {
+			panic("metrics: invalid label name " + label)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.metrics[name] = m
+}
+
+// Write sends every metric, sorted by name. A collected metric that fails
+// is logged and left out.
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make(map[string]metric, len(r.metrics))
+	for name, m := range r.metrics {
+		metrics[name] = m
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		var buf bytes.Buffer
+		mw := bufio.NewWriter(&buf)
+		if err := metrics[name].write(ctx, mw); err != nil {
+			slog.ErrorContext(ctx, "failed to collect metric", "metric", name, "error", err)
+			continue
+		}
+		if err := mw.Flush(); err != nil {
+			return err
+		}
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		if err := r.Write(req.Context(), &buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// series holds one entry per combination of label values
+type series[T any] struct {
+	labels  []string
+	mu      sync.Mutex
+	entries map[string]*entry[T]
+	init    func() T
+}
+
+type entry[T any] struct {
+	values []string
+	data   T
+}
+
+func newSeries[T any](labels []string, init func() T) *series[T] {
+	return &series[T]{labels: labels, entries: make(map[string]*entry[T]), init: init}
+}
+
+// with runs fn on the data of the label values with the lock held
+func (s *series[T]) with(values []string, fn func(data *T)) {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for labels %v", len(values), s.labels))
+	}
+	key := strings.Join(values, "\xff")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry[T]{values: append([]string(nil), values...), data: s.init()}
+		s.entries[key] = e
+	}
+	fn(&e.data)
+}
+
+// each runs fn on every entry in label order with the lock held
+func (s *series[T]) each(fn func(values []string, data T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(s.entries[key].values, s.entries[key].data)
+	}
+}
+
+// value is a counter or gauge
+type value struct {
+	name, help, kind string
+	series           *series[float64]
+}
+
+func (v *value) write(_ context.Context, w *bufio.Writer) error {
+	writeHeader(w, v.name, v.help, v.kind)
+	v.series.each(func(values []string, data float64) {
+		writeSample(w, v.name, v.series.labels, values, "", data)
+	})
+	return nil
+}
+
+// CounterVec counts events, it only goes up
+type CounterVec struct{ v *value }
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{&value{name: name, help: help, kind: "counter", series: newSeries(labels, zero)}}
+	r.register(name, labels, c.v)
+	return c
+}
+
+func (c *CounterVec) Inc(values ...string) { c.Add(1, values...) }
+
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.v.name + " cannot decrease")
+	}
+	c.v.series.with(values, func(data *float64) { *data += delta })
+}
+
+// GaugeVec holds values that go up and down
+type GaugeVec struct{ v *value }
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{&value{name: name, help: help, kind: "gauge", series: newSeries(labels, zero)}}
+	r.register(name, labels, g.v)
+	return g
+}
+
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.v.series.with(values, func(data *float64) { *data = v })
+}
+
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.v.series.with(values, func(data *float64) { *data += delta })
+}
+
+func (g *GaugeVec) Inc(values ...string) { g.Add(1, values...) }
+
+func (g *GaugeVec) Dec(values ...string) { g.Add(-1, values...) }
+
+// HistogramVec counts observations into cumulative buckets
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	series     *series[histogram]
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{name: name, help: help, buckets: buckets}
+	h.series = newSeries(labels, func() histogram { return histogram{counts: make([]uint64, len(buckets))} })
+	r.register(name, labels, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.series.with(values, func(data *histogram) {
+		for i, bound := range h.buckets {
+			if v <= bound {
+				data.counts[i]++
+			}
+		}
+		data.count++
+		data.sum += v
+	})
+}
+
+// Init makes the series of the label values show up before its first
+// observation
+func (h *HistogramVec) Init(values ...string) {
+	h.series.with(values, func(*histogram) {})
+}
+
+func (h *HistogramVec) write(_ context.Context, w *bufio.Writer) error {
+	writeHeader(w, h.name, h.help, "histogram")
+	labels := append(append([]string(nil), h.series.labels...), "le")
+	h.series.each(func(values []string, data histogram) {
+		for i, bound := range h.buckets {
+			writeSample(w, h.name, labels, append(values[:len(values):len(values)], formatFloat(bound)), "_bucket", float64(data.counts[i]))
+		}
+		writeSample(w, h.name, labels, append(values[:len(values):len(values)], "+Inf"), "_bucket", float64(data.count))
+		writeSample(w, h.name, h.series.labels, values, "_sum", data.sum)
+		writeSample(w, h.name, h.series.labels, values, "_count", float64(data.count))
+	})
+	return nil
+}
+
+// Report records one sample of a collected metric
+type Report func(value float64, labelValues ...string)
+
+// collected reads its samples at scrape time
+type collected struct {
+	name, help, kind string
+	labels           []string
+	collect          func(ctx context.Context, report Report) error
+}
+
+// NewGaugeFunc adds a gauge whose samples collect reports on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(ctx context.Context, report Report) error) {
+	r.register(name, labels, &collected{name: name, help: help, kind: "gauge", labels: labels, collect: collect})
+}
+
+// NewCounterFunc adds a counter kept elsewhere, such as by database/sql
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func(ctx context.Context, report Report) error) {
+	r.register(name, labels, &collected{name: name, help: help, kind: "counter", labels: labels, collect: collect})
+}
+
+func (c *collected) write(ctx context.Context, w *bufio.Writer) error {
+	type sample struct {
+		values []string
+		value  float64
+	}
+	var samples []sample
+	err := c.collect(ctx, func(value float64, values ...string) {
+		if len(values) != len(c.labels) {
+			panic(fmt.Sprintf("metrics: got %d label values for labels %v", len(values), c.labels))
+		}
+		samples = append(samples, sample{values, value})
+	})
+	if err != nil {
+		return err
+	}
+	writeHeader(w, c.name, c.help, c.kind)
+	for _, s := range samples {
+		writeSample(w, c.name, c.labels, s.values, "", s.value)
+	}
+	return nil
+}
+
+func zero() float64 { return 0 }
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, kind)
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, suffix string, v float64) {
+	w.WriteString(name)
+	w.WriteString(suffix)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label)
+			w.WriteString(`="`)
+			w.WriteString(labelEscaper.Replace(values[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/audit"
+	"homework1/internal/auth"
+	"homework1/internal/logging"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+// Keys under which the authenticated caller is stored in the gin.Context
+const (
+	UserIDKey = "user_id"
+	RoleKey   = "role"
+)
+
+// Authenticate requires a valid bearer access token and stores the caller's
+// user ID and role in the context
+func Authenticate(signer *auth.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			problem.Write(c, problem.New(http.StatusUnauthorized, "missing_token", "Missing bearer token"))
+			return
+		}
+
+		claims, err := signer.Parse(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Write(c, problem.New(http.StatusUnauthorized, "invalid_token", err.Error()))
+			return
+		}
+
+		c.Set(UserIDKey, claims.Subject)
+		c.Set(RoleKey, claims.Role)
+		ctx := audit.WithActor(c.Request.Context(), claims.Subject)
+		c.Request = c.Request.WithContext(logging.With(ctx, slog.String("user_id", claims.Subject.String())))
+		c.Next()
+	}
+}
+
+// OptionalAuthenticate behaves like Authenticate when an Authorization header
+// is sent and lets anonymous requests through otherwise
+func OptionalAuthenticate(signer *auth.Signer) gin.HandlerFunc {
+	authenticate := Authenticate(signer)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// UserID returns the authenticated caller's ID, if any
+func UserID(c *gin.Context) (uuid.UUID, bool) {
+	id, ok := c.Get(UserIDKey)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, ok := id.(uuid.UUID)
+	return userID, ok
+}
+
+// Role returns the authenticated caller's role, or "" when unauthenticated
+func Role(c *gin.Context) string {
+	return c.GetString(RoleKey)
+}
+
+// Actor returns the authenticated caller as a services.Actor
+func Actor(c *gin.Context) services.Actor {
+	userID, _ := UserID(c)
+	return services.Actor{UserID: userID, Role: Role(c)}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/problem"
+)
+
+// LimitBody caps the request body at limit bytes, reading past it fails
+// with an *http.MaxBytesError. It must run before anything reads the body.
+// A body announced as larger is refused before it is read.
+func LimitBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			problem.Write(c, problem.Body(&http.MaxBytesError{Limit: limit}))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+	"homework1/internal/spool"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodyMemory is how much of a request body is held in memory
+// while the handler runs, the rest waits in a temporary file
+const maxIdempotentBodyMemory = 1 << 20
+
+// Response headers stored with an idempotent response and sent on replay
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotent answers a retried request carrying an Idempotency-Key with the
+// response to the first one instead of running the handler again. Keys are
+// scoped to the route and the caller, so it must run after authentication.
+// Requests without the header pass through.
+func Idempotent(idempotency services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		// the body is hashed as it is read and kept for the handler, in a
+		// temporary file when it is large
+		body := spool.New(maxIdempotentBodyMemory)
+		defer body.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(hash, body), c.Request.Body); err != nil {
+			if body.Err() != nil {
+				problem.Error(c, err)
+				return
+			}
+			problem.Write(c, problem.Body(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(body.Reader())
+
+		scope := c.Request.Method + " " + c.FullPath()
+		if userID, ok := UserID(c); ok {
+			scope += " " + userID.String()
+		}
+
+		record, replay, err := idempotency.Begin(c.Request.Context(), scope, key, hex.EncodeToString(hash.Sum(nil)))
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		if replay {
+			replayResponse(c, record)
+			return
+		}
+
+		// the outcome must be stored even when the client has gone away
+		ctx := context.WithoutCancel(c.Request.Context())
+		abandon := func() {
+			if err := idempotency.Abandon(ctx, record.ID); err != nil {
+				slog.ErrorContext(c.Request.Context(), "failed to release idempotency key", "error", err)
+			}
+		}
+
+		// a panic is answered by Recovery with a 500, the key is released
+		// like for any server error instead of staying in progress
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					abandon()
+					panic(r)
+				}
+			}()
+			c.Next()
+		}()
+
+		// server errors are not remembered, a retry may well succeed
+		if recorder.Status() >= http.StatusInternalServerError {
+			abandon()
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		encoded, err := json.Marshal(headers)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to encode idempotent response headers", "error", err)
+			return
+		}
+		record.StatusCode = recorder.Status()
+		record.Headers = string(encoded)
+		record.Body = recorder.body.String()
+		if err := idempotency.Complete(ctx, record); err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+func replayResponse(c *gin.Context, record model.IdempotencyKey) {
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(record.Headers), &headers); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	for name, value := range headers {
+		c.Header(name, value)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(record.StatusCode)
+	_, _ = c.Writer.WriteString(record.Body)
+	c.Abort()
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+// stubIdempotency reserves every key and records what becomes of it
+type stubIdempotency struct {
+	id        uuid.UUID
+	begun     []string
+	completed []model.IdempotencyKey
+	abandoned []uuid.UUID
+}
+
+func (s *stubIdempotency) Begin(_ context.Context, scope, key, fingerprint string) (model.IdempotencyKey, bool, error) {
+	s.begun = append(s.begun, fingerprint)
+	return model.IdempotencyKey{ID: s.id, Scope: scope, Key: key, Fingerprint: fingerprint}, false, nil
+}
+
+func (s *stubIdempotency) Complete(_ context.Context, record model.IdempotencyKey) error {
+	s.completed = append(s.completed, record)
+	return nil
+}
+
+func (s *stubIdempotency) Abandon(_ context.Context, id uuid.UUID) error {
+	s.abandoned = append(s.abandoned, id)
+	return nil
+}
+
+func TestIdempotentOutcomes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		handler   gin.HandlerFunc
+		status    int
+		completed bool
+	}{
+		{"created", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"ok": true}) }, http.StatusCreated, true},
+		{"client error", func(c *gin.Context) { c.Status(http.StatusUnprocessableEntity) }, http.StatusUnprocessableEntity, true},
+		{"server error", func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) }, http.StatusServiceUnavailable, false},
+		{"panic", func(c *gin.Context) { panic("boom") }, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		stub := &stubIdempotency{id: uuid.New()}
+		router := gin.New()
+		router.Use(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		router.POST("/products", Idempotent(stub), tt.handler)
+
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"chair"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if tt.completed {
+			if len(stub.completed) != 1 || stub.completed[0].StatusCode != tt.status || len(stub.abandoned) != 0 {
+				t.Errorf("%s: completed %v, abandoned %v, want the response stored", tt.name, stub.completed, stub.abandoned)
+			}
+		} else if len(stub.abandoned) != 1 || stub.abandoned[0] != stub.id || len(stub.completed) != 0 {
+			t.Errorf("%s: completed %v, abandoned %v, want the key released", tt.name, stub.completed, stub.abandoned)
+		}
+	}
+}
+
+func TestIdempotentFingerprintsWholeBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// the larger body no longer fits in memory and is spooled to a file
+	for _, size := range []int{100, maxIdempotentBodyMemory + 12345} {
+		body := bytes.Repeat([]byte("0123456789"), size/10+1)[:size]
+		stub := &stubIdempotency{id: uuid.New()}
+		router := gin.New()
+		var seen []byte
+		router.POST("/products:action", Idempotent(stub), func(c *gin.Context) {
+			seen, _ = io.ReadAll(c.Request.Body)
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/products:action", bytes.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		sum := sha256.Sum256(body)
+		if len(stub.begun) != 1 || stub.begun[0] != hex.EncodeToString(sum[:]) {
+			t.Errorf("%d bytes: fingerprints %v, want %x", size, stub.begun, sum)
+		}
+		if !bytes.Equal(seen, body) {
+			t.Errorf("%d bytes: handler read %d bytes, want the whole body", size, len(seen))
+		}
+	}
+}
+
+func TestIdempotentBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, length := range []int64{-1, 2048} {
+		stub := &stubIdempotency{id: uuid.New()}
+		router := gin.New()
+		router.POST("/products:action", LimitBody(1024), Idempotent(stub), func(c *gin.Context) {
+			t.Error("the handler ran on a body past the limit")
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/products:action", bytes.NewReader(make([]byte, 2048)))
+		// -1 is a body of unknown length, cut off while it is read
+		req.ContentLength = length
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "body_too_large") {
+			t.Errorf("length %d: %d %s, want 413 body_too_large", length, w.Code, w.Body)
+		}
+		if len(stub.begun) != 0 {
+			t.Errorf("length %d: key reserved for a rejected body", length)
+		}
+	}
+}
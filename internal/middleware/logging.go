@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/logging"
+	"homework1/internal/problem"
+)
+
+// RequestIDHeader carries the ID of a request, echoed in the response
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is where the request ID is stored in the gin.Context
+const RequestIDKey = "request_id"
+
+const maxRequestIDLength = 128
+
+// RequestID takes the request ID from the client when it is sensible and
+// generates one otherwise. It is logged with every record of the request.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String("request_id", id)))
+		c.Next()
+	}
+}
+
+// validRequestID accepts printable ASCII of a bounded length, anything else
+// could forge log lines or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger logs every request once it is served. The route template is added
+// to the request's records, Authenticate adds the user ID.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String("route", c.FullPath())))
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", logging.Milliseconds(time.Since(start))),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery answers a panicking request with a 500 problem and logs the panic
+// with its stack
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logger.ErrorContext(c.Request.Context(), "panic", "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+		problem.Write(c, problem.New(http.StatusInternalServerError, "internal_error", "an unexpected error occurred"))
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/metrics"
+)
+
+// UnmatchedRoute labels requests that no route matched, their paths could
+// be anything
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod labels requests with a method outside the standard set, a
+// client could otherwise make up any number of them
+const OtherMethod = "other"
+
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Metrics records the count, status and latency of every request by its
+// route template
+func Metrics(m *metrics.HTTP) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.Begin()
+		c.Next()
+		status := c.Writer.Status()
+		m.End(methodLabel(c.Request.Method), routeLabel(c, status), status, time.Since(start))
+	}
+}
+
+func methodLabel(method string) string {
+	if standardMethods[method] {
+		return method
+	}
+	return OtherMethod
+}
+
+// routeLabel is the route template, with the action filled in for custom
+// methods such as /products:import. A custom method that does not exist is
+// not found and keeps the template.
+func routeLabel(c *gin.Context, status int) string {
+	route := c.FullPath()
+	if route == "" {
+		return UnmatchedRoute
+	}
+	if strings.HasSuffix(route, ":action") && status != http.StatusNotFound {
+		route = strings.TrimSuffix(route, ":action") + c.Param("action")
+	}
+	return route
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/metrics"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := metrics.NewRegistry()
+	router := gin.New()
+	router.Use(Metrics(metrics.NewHTTP(registry)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/products/:id", ok)
+	router.POST("/products:action", ok)
+	router.Handle("PURGE", "/cache", ok)
+
+	requests := []struct{ method, path string }{
+		{http.MethodGet, "/products/1"},
+		{http.MethodGet, "/products/2"},
+		{http.MethodPost, "/products:import"},
+		{"PURGE", "/cache"},
+		{"BREW", "/products/1"},
+		{http.MethodGet, "/nowhere/42"},
+	}
+	for _, r := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	var b strings.Builder
+	if err := registry.Write(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/products/:id",status="200"} 2`,
+		`http_requests_total{method="POST",route="/products:import",status="200"} 1`,
+		`http_requests_total{method="other",route="/cache",status="200"} 1`,
+		`http_requests_total{method="other",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics lack %s:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"PURGE", "BREW", "/nowhere", "/products/1"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("metrics show %s:\n%s", unwanted, out)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+// RequirePermission rejects callers whose role lacks the permission and
+// records the denied request. It must run after Authenticate.
+func RequirePermission(rbac services.RBACService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := Role(c)
+		if rbac.HasPermission(role, permission) {
+			c.Next()
+			return
+		}
+
+		userID, _ := UserID(c)
+		err := rbac.RecordDenial(c.Request.Context(), model.AccessDenial{
+			UserID:     userID,
+			Role:       role,
+			Permission: permission,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+		})
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to record access denial", "error", err)
+		}
+
+		problem.Write(c, problem.New(http.StatusForbidden, "missing_permission", "Missing permission "+permission))
+	}
+}
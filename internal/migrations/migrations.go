@@ -0,0 +1,346 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+// SourceDir is where the migration files live relative to the module root,
+// new migrations are created there
+const SourceDir = "internal/migrations/sql"
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownVersion   = errors.New("database has a migration this binary does not know")
+	ErrSchemaBehind     = errors.New("database schema is behind")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// conditionPrefix starts an optional first line of an up file holding a
+// query, the migration is recorded without running when it returns false.
+// It keeps features such as FTS5 that depend on how the database was built
+// from failing the migration, their down file must cope with either case.
+const conditionPrefix = "-- +if "
+
+type Migration struct {
+	Version   int64
+	Name      string
+	Up        string
+	Down      string
+	Checksum  string
+	Condition string
+
+	// Skipped is set by Up on migrations recorded without running
+	Skipped bool
+}
+
+// Status is a known migration together with its state in the database
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"`
+}
+
+// schemaMigration is one row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Checksum  string    `gorm:"type:varchar(64);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New loads the embedded migrations for the dialect, e.g. "sqlite"
+func New(db *gorm.DB, dialect string) (*Migrator, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			if first, _, _ := strings.Cut(m.Up, "\n"); strings.HasPrefix(first, conditionPrefix) {
+				m.Condition = strings.TrimSpace(strings.TrimPrefix(first, conditionPrefix))
+			}
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// applied reads schema_migrations without changing the database, so that
+// readiness probes can call Check. A database without the table has nothing
+// applied, Up creates it.
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		// HasTable reports a database it cannot reach as missing the table
+		if err := m.db.Exec("SELECT 1").Error; err != nil {
+			return nil, err
+		}
+		return map[int64]schemaMigration{}, nil
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]schemaMigration{}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+			s.Modified = row.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations still to apply. It fails when an applied
+// migration was edited afterwards or is unknown to this binary.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int64]bool{}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		row, ok := applied[migration.Version]
+		if !ok {
+			pending = append(pending, migration)
+			continue
+		}
+		if row.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	for version, row := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrUnknownVersion, version, row.Name)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns ErrSchemaBehind when there are pending migrations
+func (m *Migrator) Check() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending, next is %04d_%s", ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Up applies pending migrations up to and including target, 0 means all.
+// Each migration runs in its own transaction.
+func (m *Migrator) Up(target int64) ([]Migration, error) {
+	if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if migration.Condition != "" {
+			var met bool
+			if err := m.db.Raw(migration.Condition).Scan(&met).Error; err != nil {
+				return done, fmt.Errorf("condition of %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			migration.Skipped = !met
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if !migration.Skipped {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %04d_%s has no down file", migration.Version, migration.Name)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Create writes an empty up/down pair into every dialect directory under
+// root, numbered after the newest migration of any dialect so the sets stay
+// aligned, and returns the paths written
+func Create(root, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	dialects, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	var latest int64
+	for _, dialect := range dialects {
+		if !dialect.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, dialect.Name())
+		dirs = append(dirs, dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+				version, _ := strconv.ParseInt(match[1], 10, 64)
+				latest = max(latest, version)
+			}
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", latest+1, name)
+	var created []string
+	for _, dir := range dirs {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, base+"."+direction+".sql")
+			if err := os.WriteFile(file, []byte("-- "+base+" "+direction+"\n"), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
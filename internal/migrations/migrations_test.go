@@ -0,0 +1,232 @@
+package migrations
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func tables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	names, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func applied(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, s := range statuses {
+		if s.Modified {
+			t.Errorf("%04d_%s is modified", s.Version, s.Name)
+		}
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func TestUpStatusDown(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := len(m.migrations)
+
+	if err := m.Check(); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check on an empty database = %v, want ErrSchemaBehind", err)
+	}
+	if got := applied(t, m); len(got) != 0 {
+		t.Fatalf("applied before up: %v", got)
+	}
+
+	done, err := m.Up(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 || done[1].Version != 2 {
+		t.Fatalf("Up(2) applied %d migrations", len(done))
+	}
+	if err := m.Check(); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check after Up(2) = %v, want ErrSchemaBehind", err)
+	}
+
+	done, err = m.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != all-2 {
+		t.Fatalf("Up(0) applied %d migrations, want %d", len(done), all-2)
+	}
+	if err := m.Check(); err != nil {
+		t.Fatalf("Check after Up(0) = %v", err)
+	}
+	if got := applied(t, m); len(got) != all {
+		t.Fatalf("applied %v, want all %d", got, all)
+	}
+	for _, table := range []string{"users", "products", "refresh_tokens", "product_history", "idempotency_keys"} {
+		if !slices.Contains(tables(t, db), table) {
+			t.Errorf("table %s is missing after up", table)
+		}
+	}
+	if done, err := m.Up(0); err != nil || len(done) != 0 {
+		t.Fatalf("second Up(0) applied %d migrations, error %v", len(done), err)
+	}
+
+	done, err = m.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].Version != m.migrations[all-1].Version {
+		t.Fatalf("Down(1) rolled back %v", done)
+	}
+	if got := applied(t, m); len(got) != all-1 {
+		t.Fatalf("applied %v after Down(1)", got)
+	}
+
+	if _, err := m.Down(all); err != nil {
+		t.Fatal(err)
+	}
+	if got := applied(t, m); len(got) != 0 {
+		t.Fatalf("applied %v after rolling back everything", got)
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"schema_migrations"}) {
+		t.Errorf("tables left after rolling back everything: %v", got)
+	}
+
+	// the down files leave a schema the up files can rebuild
+	if _, err := m.Up(0); err != nil {
+		t.Fatalf("Up after rolling back everything: %v", err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(1); err != nil {
+		t.Fatal(err)
+	}
+	m.migrations[0].Checksum = "edited"
+	if err := m.Check(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Check = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestConditionSkipsMigration(t *testing.T) {
+	db := newTestDB(t)
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "kept", Up: "CREATE TABLE kept (x int)", Condition: "SELECT 1 = 1", Checksum: "1"},
+		{Version: 2, Name: "skipped", Up: "CREATE TABLE skipped (x int)", Condition: "SELECT 1 = 0", Checksum: "2"},
+	}}
+	done, err := m.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 || done[0].Skipped || !done[1].Skipped {
+		t.Fatalf("Up = %+v, want kept applied and skipped skipped", done)
+	}
+	if got := tables(t, db); !slices.Equal(got, []string{"kept", "schema_migrations"}) {
+		t.Errorf("tables %v", got)
+	}
+	if err := m.Check(); err != nil {
+		t.Errorf("a skipped migration counts as applied, Check = %v", err)
+	}
+}
+
+func TestDialectsAreAligned(t *testing.T) {
+	sqlite, err := load("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dialect := range []string{"postgres", "mysql"} {
+		migrations, err := load(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(migrations) != len(sqlite) {
+			t.Fatalf("%s has %d migrations, sqlite %d", dialect, len(migrations), len(sqlite))
+		}
+		for i, m := range migrations {
+			if m.Version != sqlite[i].Version || m.Name != sqlite[i].Name {
+				t.Errorf("%s has %04d_%s where sqlite has %04d_%s", dialect, m.Version, m.Name, sqlite[i].Version, sqlite[i].Name)
+			}
+			if m.Down == "" {
+				t.Errorf("%s %04d_%s has no down file", dialect, m.Version, m.Name)
+			}
+		}
+	}
+}
+
+// TestBackfillMatchesDriverTimes checks that rows backfilled by 0005 carry
+// times in the text format the driver writes, SQLite compares them as text
+func TestBackfillMatchesDriverTimes(t *testing.T) {
+	db := newTestDB(t)
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(4); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec("INSERT INTO users (id, first_name, last_name, email, password, role) VALUES ('old', 'A', 'B', 'old@example.com', 'x', 'seller')").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().UTC().Add(-time.Second)
+	if _, err := m.Up(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO users (id, first_name, last_name, email, password, role, created_at) VALUES ('before', 'A', 'B', 'before@example.com', 'x', 'seller', ?)", before).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var backfilled, written string
+	db.Raw("SELECT CAST(created_at AS TEXT) FROM users WHERE id = 'old'").Scan(&backfilled)
+	db.Raw("SELECT CAST(created_at AS TEXT) FROM users WHERE id = 'before'").Scan(&written)
+	format := regexp.MustCompile(`^\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)?\+00:00$`)
+	for _, value := range []string{backfilled, written} {
+		if !format.MatchString(value) {
+			t.Errorf("created_at %q is not in the driver format", value)
+		}
+	}
+
+	var newer []string
+	db.Raw("SELECT id FROM users WHERE created_at > ?", before).Scan(&newer)
+	if !slices.Equal(newer, []string{"old"}) {
+		t.Errorf("users created after %s: %v, want the backfilled one", before, newer)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS access_denials;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS refresh_tokens;
+ALTER TABLE users MODIFY password varchar(100) NOT NULL;
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission = 'data:purge';
+DROP INDEX idx_products_deleted_at ON products;
+ALTER TABLE products DROP COLUMN deleted_at;
+DROP INDEX idx_users_deleted_at ON users;
+ALTER TABLE users DROP COLUMN deleted_at;
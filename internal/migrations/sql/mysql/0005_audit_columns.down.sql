@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN updated_by, DROP COLUMN created_by, DROP COLUMN updated_at, DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN updated_by, DROP COLUMN created_by, DROP COLUMN updated_at, DROP COLUMN created_at;
@@ -0,0 +1,12 @@
+-- datetime keeps no zone, GORM writes UTC so the backfill does too
+ALTER TABLE users ADD COLUMN created_at datetime(3) NULL;
+ALTER TABLE users ADD COLUMN updated_at datetime(3) NULL;
+ALTER TABLE users ADD COLUMN created_by char(36) NULL;
+ALTER TABLE users ADD COLUMN updated_by char(36) NULL;
+UPDATE users SET created_at = UTC_TIMESTAMP(3), updated_at = UTC_TIMESTAMP(3);
+
+ALTER TABLE products ADD COLUMN created_at datetime(3) NULL;
+ALTER TABLE products ADD COLUMN updated_at datetime(3) NULL;
+ALTER TABLE products ADD COLUMN created_by char(36) NULL;
+ALTER TABLE products ADD COLUMN updated_by char(36) NULL;
+UPDATE products SET created_at = UTC_TIMESTAMP(3), updated_at = UTC_TIMESTAMP(3);
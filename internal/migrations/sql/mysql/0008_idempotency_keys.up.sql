@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    id char(36) PRIMARY KEY,
+    scope varchar(300) NOT NULL,
+    idempotency_key varchar(255) NOT NULL,
+    fingerprint varchar(64) NOT NULL,
+    status_code integer NOT NULL DEFAULT 0,
+    headers text,
+    body mediumtext,
+    created_at datetime(3) NULL,
+    expires_at datetime(3) NOT NULL
+);
+CREATE UNIQUE INDEX idx_idempotency_keys_scope_key ON idempotency_keys(scope, idempotency_key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
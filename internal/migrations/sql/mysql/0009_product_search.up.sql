@@ -0,0 +1,2 @@
+-- Product search matches word prefixes with LIKE here, FTS5 is SQLite only
+CREATE INDEX idx_products_name ON products(name);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS users (
+    id char(36) PRIMARY KEY,
+    first_name varchar(100) NOT NULL,
+    last_name varchar(100) NOT NULL,
+    email varchar(100) NOT NULL,
+    password varchar(100) NOT NULL,
+    role varchar(100) NOT NULL,
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id char(36) PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    price decimal NOT NULL,
+    quantity integer NOT NULL,
+    user_id char(36) NOT NULL,
+    CONSTRAINT fk_users_product FOREIGN KEY (user_id) REFERENCES users(id)
+);
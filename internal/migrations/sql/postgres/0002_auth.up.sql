@@ -0,0 +1,29 @@
+ALTER TABLE users ALTER COLUMN password TYPE varchar(255);
+
+CREATE TABLE refresh_tokens (
+    id char(36) PRIMARY KEY,
+    user_id char(36) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+CREATE TABLE role_permissions (
+    role varchar(100),
+    permission varchar(100),
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE access_denials (
+    id char(36) PRIMARY KEY,
+    user_id char(36),
+    role varchar(100),
+    permission varchar(100) NOT NULL,
+    method varchar(10) NOT NULL,
+    path varchar(255) NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX idx_access_denials_created_at ON access_denials(created_at);
+CREATE INDEX idx_access_denials_user_id ON access_denials(user_id);
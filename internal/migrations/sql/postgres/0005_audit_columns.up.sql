@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN created_at timestamptz;
+ALTER TABLE users ADD COLUMN updated_at timestamptz;
+ALTER TABLE users ADD COLUMN created_by char(36);
+ALTER TABLE users ADD COLUMN updated_by char(36);
+UPDATE users SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+
+ALTER TABLE products ADD COLUMN created_at timestamptz;
+ALTER TABLE products ADD COLUMN updated_at timestamptz;
+ALTER TABLE products ADD COLUMN created_by char(36);
+ALTER TABLE products ADD COLUMN updated_by char(36);
+UPDATE products SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_history;
+DROP TABLE IF EXISTS product_history;
@@ -0,0 +1,22 @@
+-- Change history, entries keep no foreign key so they survive a purge
+CREATE TABLE product_history (
+    id char(36) PRIMARY KEY,
+    entity_id char(36) NOT NULL,
+    operation varchar(16) NOT NULL,
+    snapshot text NOT NULL,
+    diff text NOT NULL,
+    actor_id char(36),
+    changed_at timestamptz NOT NULL
+);
+CREATE INDEX idx_product_history_entity_id ON product_history(entity_id, changed_at);
+
+CREATE TABLE user_history (
+    id char(36) PRIMARY KEY,
+    entity_id char(36) NOT NULL,
+    operation varchar(16) NOT NULL,
+    snapshot text NOT NULL,
+    diff text NOT NULL,
+    actor_id char(36),
+    changed_at timestamptz NOT NULL
+);
+CREATE INDEX idx_user_history_entity_id ON user_history(entity_id, changed_at);
@@ -0,0 +1,2 @@
+-- Product search matches word prefixes with LIKE here, FTS5 is SQLite only
+CREATE INDEX idx_products_name_lower ON products (LOWER(name) varchar_pattern_ops);
@@ -0,0 +1,22 @@
+-- Tables as created by AutoMigrate before versioned migrations, IF NOT EXISTS
+-- lets databases from that time adopt the migration history
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` uuid,
+    `first_name` varchar(100) NOT NULL,
+    `last_name` varchar(100) NOT NULL,
+    `email` varchar(100) NOT NULL,
+    `password` varchar(100) NOT NULL,
+    `role` varchar(100) NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+
+CREATE TABLE IF NOT EXISTS `products` (
+    `id` uuid,
+    `name` varchar(100) NOT NULL,
+    `price` decimal NOT NULL,
+    `quantity` integer NOT NULL,
+    `user_id` uuid NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_product` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
@@ -0,0 +1,5 @@
+-- Only exact for two digit currencies, the currency itself is lost
+ALTER TABLE `products` ADD COLUMN `price` decimal NOT NULL DEFAULT 0;
+UPDATE `products` SET `price` = `price_amount` / 100.0;
+ALTER TABLE `products` DROP COLUMN `price_currency`;
+ALTER TABLE `products` DROP COLUMN `price_amount`;
@@ -0,0 +1,5 @@
+-- Prices move from a float to integer minor units, existing rows are USD
+ALTER TABLE `products` ADD COLUMN `price_amount` integer NOT NULL DEFAULT 0;
+ALTER TABLE `products` ADD COLUMN `price_currency` varchar(3) NOT NULL DEFAULT 'USD';
+UPDATE `products` SET `price_amount` = CAST(ROUND(`price` * 100) AS INTEGER), `price_currency` = 'USD';
+ALTER TABLE `products` DROP COLUMN `price`;
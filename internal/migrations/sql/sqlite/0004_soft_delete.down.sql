@@ -0,0 +1,5 @@
+DELETE FROM `role_permissions` WHERE `permission` = 'data:purge';
+DROP INDEX IF EXISTS `idx_products_deleted_at`;
+ALTER TABLE `products` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_users_deleted_at`;
+ALTER TABLE `users` DROP COLUMN `deleted_at`;
@@ -0,0 +1,8 @@
+ALTER TABLE `users` ADD COLUMN `deleted_at` datetime;
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
+ALTER TABLE `products` ADD COLUMN `deleted_at` datetime;
+CREATE INDEX `idx_products_deleted_at` ON `products`(`deleted_at`);
+
+-- an already seeded permission table does not pick up new defaults
+INSERT INTO `role_permissions` (`role`, `permission`)
+SELECT 'admin', 'data:purge' WHERE EXISTS (SELECT 1 FROM `role_permissions`);
@@ -0,0 +1,14 @@
+-- SQLite cannot add a column with a non constant default, existing rows are
+-- backfilled instead. Times are text here and compared as such, the backfill
+-- uses the UTC format the driver writes rather than CURRENT_TIMESTAMP.
+ALTER TABLE `users` ADD COLUMN `created_at` datetime;
+ALTER TABLE `users` ADD COLUMN `updated_at` datetime;
+ALTER TABLE `users` ADD COLUMN `created_by` char(36);
+ALTER TABLE `users` ADD COLUMN `updated_by` char(36);
+UPDATE `users` SET `created_at` = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), `updated_at` = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+
+ALTER TABLE `products` ADD COLUMN `created_at` datetime;
+ALTER TABLE `products` ADD COLUMN `updated_at` datetime;
+ALTER TABLE `products` ADD COLUMN `created_by` char(36);
+ALTER TABLE `products` ADD COLUMN `updated_by` char(36);
+UPDATE `products` SET `created_at` = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), `updated_at` = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
@@ -0,0 +1,24 @@
+-- Change history, entries keep no foreign key so they survive a purge
+CREATE TABLE `product_history` (
+    `id` uuid,
+    `entity_id` uuid NOT NULL,
+    `operation` varchar(16) NOT NULL,
+    `snapshot` text NOT NULL,
+    `diff` text NOT NULL,
+    `actor_id` uuid,
+    `changed_at` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_product_history_entity_id` ON `product_history`(`entity_id`, `changed_at`);
+
+CREATE TABLE `user_history` (
+    `id` uuid,
+    `entity_id` uuid NOT NULL,
+    `operation` varchar(16) NOT NULL,
+    `snapshot` text NOT NULL,
+    `diff` text NOT NULL,
+    `actor_id` uuid,
+    `changed_at` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_user_history_entity_id` ON `user_history`(`entity_id`, `changed_at`);
@@ -0,0 +1,2 @@
+ALTER TABLE `products` DROP COLUMN `version`;
+ALTER TABLE `users` DROP COLUMN `version`;
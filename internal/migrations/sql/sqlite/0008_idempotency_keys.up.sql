@@ -0,0 +1,14 @@
+CREATE TABLE `idempotency_keys` (
+    `id` uuid,
+    `scope` varchar(300) NOT NULL,
+    `idempotency_key` varchar(255) NOT NULL,
+    `fingerprint` varchar(64) NOT NULL,
+    `status_code` integer NOT NULL DEFAULT 0,
+    `headers` text,
+    `body` text,
+    `created_at` datetime,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+CREATE UNIQUE INDEX `idx_idempotency_keys_scope_key` ON `idempotency_keys`(`scope`, `idempotency_key`);
+CREATE INDEX `idx_idempotency_keys_expires_at` ON `idempotency_keys`(`expires_at`);
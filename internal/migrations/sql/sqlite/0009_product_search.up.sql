@@ -0,0 +1,39 @@
+-- +if SELECT sqlite_compileoption_used('ENABLE_FTS5')
+-- Full-text index over product names, only created by builds with FTS5, see
+-- the sqlite_fts5 build tag, search falls back to LIKE without it. Rowids of
+-- a table without an INTEGER PRIMARY KEY may change on VACUUM, so the index
+-- is keyed by the rowid of products_fts_docs, which maps it to the product
+-- ID. The triggers look rows up by rowid and product ID on both tables.
+-- Soft deleted products stay indexed and are filtered on search.
+CREATE TABLE `products_fts_docs` (
+    `doc_id` INTEGER PRIMARY KEY,
+    `product_id` char(36) NOT NULL UNIQUE
+);
+
+CREATE VIRTUAL TABLE `products_fts` USING fts5(
+    `name`,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO `products_fts_docs` (`product_id`) SELECT `id` FROM `products`;
+INSERT INTO `products_fts` (`rowid`, `name`)
+    SELECT `products_fts_docs`.`doc_id`, `products`.`name` FROM `products`
+    JOIN `products_fts_docs` ON `products_fts_docs`.`product_id` = `products`.`id`;
+
+CREATE TRIGGER `products_fts_insert` AFTER INSERT ON `products` BEGIN
+    INSERT INTO `products_fts_docs` (`product_id`) VALUES (new.`id`);
+    INSERT INTO `products_fts` (`rowid`, `name`) VALUES (
+        (SELECT `doc_id` FROM `products_fts_docs` WHERE `product_id` = new.`id`), new.`name`);
+END;
+
+CREATE TRIGGER `products_fts_update` AFTER UPDATE OF `id`, `name` ON `products` BEGIN
+    UPDATE `products_fts_docs` SET `product_id` = new.`id` WHERE `product_id` = old.`id`;
+    UPDATE `products_fts` SET `name` = new.`name` WHERE `rowid` =
+        (SELECT `doc_id` FROM `products_fts_docs` WHERE `product_id` = new.`id`);
+END;
+
+CREATE TRIGGER `products_fts_delete` AFTER DELETE ON `products` BEGIN
+    DELETE FROM `products_fts` WHERE `rowid` =
+        (SELECT `doc_id` FROM `products_fts_docs` WHERE `product_id` = old.`id`);
+    DELETE FROM `products_fts_docs` WHERE `product_id` = old.`id`;
+END;
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// History operations
+const (
+	HistoryInsert  = "insert"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryPurge   = "purge"
+)
+
+// HistoryEntry is one recorded change of a product or user. Snapshot is the
+// row as JSON after the change, or before it for a purge, and Diff maps each
+// changed field to {"from": ..., "to": ...}. Entries have no foreign key so
+// they outlive the row.
+type HistoryEntry struct{
+
+	ID uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+
+	EntityID uuid.UUID `json:"entity_id" gorm:"type:char(36);not null;index"`
+
+	Operation string `json:"operation" gorm:"type:varchar(16);not null"`
+
+	Snapshot string `json:"-" gorm:"type:text;not null"`
+
+	Diff string `json:"-" gorm:"type:text;not null"`
+
+	ActorID *uuid.UUID `json:"actor_id" gorm:"type:char(36)"`
+
+	ChangedAt time.Time `json:"changed_at" gorm:"not null"`
+
+}
+
+type historyEntryJSON HistoryEntry
+
+func (h HistoryEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		historyEntryJSON
+		Snapshot json.RawMessage `json:"snapshot"`
+		Diff     json.RawMessage `json:"diff"`
+	}{historyEntryJSON(h), json.RawMessage(h.Snapshot), json.RawMessage(h.Diff)})
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey stores the response to a request sent with an
+// Idempotency-Key header so a retry can be answered with it. StatusCode is 0
+// while the first request is still being processed.
+type IdempotencyKey struct{
+
+	ID uuid.UUID `gorm:"type:char(36);primaryKey"`
+
+	// Scope separates keys of different callers and endpoints
+	Scope string `gorm:"type:varchar(300);not null;uniqueIndex:idx_idempotency_keys_scope_key"`
+
+	Key string `gorm:"column:idempotency_key;type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_scope_key"`
+
+	// Fingerprint is the SHA-256 of the request body
+	Fingerprint string `gorm:"type:varchar(64);not null"`
+
+	StatusCode int `gorm:"not null;default:0"`
+
+	// Headers is a JSON object of the response headers worth replaying
+	Headers string `gorm:"type:text"`
+
+	Body string `gorm:"type:text"`
+
+	CreatedAt time.Time
+
+	ExpiresAt time.Time `gorm:"not null;index"`
+
+}
+
+// Completed reports whether the response has been stored
+func (k IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
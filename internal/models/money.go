@@ -0,0 +1,234 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for rows that predate multi currency prices
+const DefaultCurrency Currency = "USD"
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooPrecise       = errors.New("amount has more decimal places than the currency allows")
+	ErrAmountOverflow   = errors.New("amount out of range")
+)
+
+// Currency is an ISO 4217 alphabetic code
+type Currency string
+
+// currencyExponents holds the number of minor unit digits of each supported
+// ISO 4217 currency
+var currencyExponents = map[Currency]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2,
+	"CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2,
+	"INR": 2, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KHR": 2, "KRW": 0,
+	"KWD": 3, "LYD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2, "OMR": 3,
+	"PHP": 2, "PLN": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Exponent returns the number of minor unit digits of the currency
+func (c Currency) Exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+func (c Currency) Valid() bool {
+	_, ok := currencyExponents[c]
+	return ok
+}
+
+// Currencies lists the supported currencies in alphabetical order
+func Currencies() []Currency {
+	currencies := make([]Currency, 0, len(currencyExponents))
+	for c := range currencyExponents {
+		currencies = append(currencies, c)
+	}
+	slices.Sort(currencies)
+	return currencies
+}
+
+// Scan implements sql.Scanner
+func (c *Currency) Scan(value any) error {
+	switch v := value.(type) {
+	case string:
+		*c = Currency(v)
+	case []byte:
+		*c = Currency(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Currency", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (c Currency) Value() (driver.Value, error) {
+	if !c.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return string(c), nil
+}
+
+// Money is an exact amount in integer minor units (cents for USD) of a
+// currency. In JSON the amount is a decimal string, {"amount": "12.34",
+// "currency": "USD"}.
+type Money struct {
+	Amount int64 `gorm:"not null;default:0"`
+
+	Currency Currency `gorm:"type:varchar(3);not null;default:'USD'"`
+}
+
+func NewMoney(minor int64, currency Currency) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "12.34" into minor units,
+// rejecting more decimal places than the currency has
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.Exponent()
+	if err != nil {
+		return Money{}, err
+	}
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || strings.ContainsAny(whole+frac, "+-eE ") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q for %s", ErrTooPrecise, amount, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	// the sign is parsed along with the digits, math.MinInt64 has no
+	// positive counterpart
+	digits := whole + frac
+	if negative {
+		digits = "-" + digits
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrAmountOverflow
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Decimal formats the amount as a decimal string without the currency
+func (m Money) Decimal() string {
+	exp, err := m.Currency.Exponent()
+	if err != nil || exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absInt64(amount), 10)
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Mul multiplies the amount by a whole quantity, e.g. unit price * stock
+func (m Money) Mul(quantity int64) (Money, error) {
+	if quantity != 0 && m.Amount != 0 {
+		product := m.Amount * quantity
+		// math.MinInt64 * -1 wraps to itself and passes the division check
+		if product/quantity != m.Amount || (m.Amount == -1 && quantity == math.MinInt64) || (m.Amount == math.MinInt64 && quantity == -1) {
+			return Money{}, ErrAmountOverflow
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+	return Money{Amount: 0, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or 1 comparing m to other
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+type moneyJSON struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%w: amount must be a decimal string", ErrInvalidAmount)
+	}
+	parsed, err := ParseMoney(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		minor    int64
+		err      error
+	}{
+		{"12.34", "USD", 1234, nil},
+		{"12.3", "USD", 1230, nil},
+		{"12", "USD", 1200, nil},
+		{"12.", "USD", 1200, nil},
+		{"0.01", "USD", 1, nil},
+		{" 7.5 ", "EUR", 750, nil},
+		{"12.340", "USD", 1234, nil},
+		{"12.345", "USD", 0, ErrTooPrecise},
+		{"0.001", "USD", 0, ErrTooPrecise},
+		{"100", "JPY", 100, nil},
+		{"100.0", "JPY", 100, nil},
+		{"100.5", "JPY", 0, ErrTooPrecise},
+		{"1.234", "KWD", 1234, nil},
+		{"1.2345", "KWD", 0, ErrTooPrecise},
+		{"-12.34", "USD", -1234, nil},
+		{"-0.01", "USD", -1, nil},
+		{"-0", "USD", 0, nil},
+		{"92233720368547758.07", "USD", math.MaxInt64, nil},
+		{"-92233720368547758.08", "USD", math.MinInt64, nil},
+		{"92233720368547758.08", "USD", 0, ErrAmountOverflow},
+		{"-92233720368547758.09", "USD", 0, ErrAmountOverflow},
+		{"9223372036854775808", "JPY", 0, ErrAmountOverflow},
+		{"", "USD", 0, ErrInvalidAmount},
+		{".5", "USD", 0, ErrInvalidAmount},
+		{"-", "USD", 0, ErrInvalidAmount},
+		{"--1", "USD", 0, ErrInvalidAmount},
+		{"+1", "USD", 0, ErrInvalidAmount},
+		{"1e3", "USD", 0, ErrInvalidAmount},
+		{"1 000", "USD", 0, ErrInvalidAmount},
+		{"1,5", "USD", 0, ErrInvalidAmount},
+		{"1.-5", "USD", 0, ErrInvalidAmount},
+		{"abc", "USD", 0, ErrInvalidAmount},
+		{"1.00", "XYZ", 0, ErrUnknownCurrency},
+		{"1.00", "", 0, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.amount, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseMoney(%q, %s) error %v, want %v", tt.amount, tt.currency, err, tt.err)
+			continue
+		}
+		if err == nil && (m.Amount != tt.minor || m.Currency != tt.currency) {
+			t.Errorf("ParseMoney(%q, %s) = %d %s, want %d", tt.amount, tt.currency, m.Amount, m.Currency, tt.minor)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		currency Currency
+		want     string
+	}{
+		{1234, "USD", "12.34"},
+		{5, "USD", "0.05"},
+		{0, "USD", "0.00"},
+		{-5, "USD", "-0.05"},
+		{-1234, "USD", "-12.34"},
+		{100, "JPY", "100"},
+		{-100, "JPY", "-100"},
+		{1, "KWD", "0.001"},
+		{math.MaxInt64, "USD", "92233720368547758.07"},
+		{math.MinInt64, "USD", "-92233720368547758.08"},
+		{math.MinInt64, "JPY", "-9223372036854775808"},
+	}
+	for _, tt := range tests {
+		m := NewMoney(tt.minor, tt.currency)
+		got := m.Decimal()
+		if got != tt.want {
+			t.Errorf("Decimal of %d %s = %q, want %q", tt.minor, tt.currency, got, tt.want)
+			continue
+		}
+		// every formatted amount parses back to itself
+		if back, err := ParseMoney(got, tt.currency); err != nil || back != m {
+			t.Errorf("ParseMoney(%q, %s) = %v, %v, want %v", got, tt.currency, back, err, m)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	usd := func(minor int64) Money { return NewMoney(minor, "USD") }
+	tests := []struct {
+		name string
+		op   func() (Money, error)
+		want Money
+		err  error
+	}{
+		{"add", func() (Money, error) { return usd(150).Add(usd(250)) }, usd(400), nil},
+		{"add a negative", func() (Money, error) { return usd(150).Add(usd(-250)) }, usd(-100), nil},
+		{"add to max", func() (Money, error) { return usd(math.MaxInt64 - 1).Add(usd(1)) }, usd(math.MaxInt64), nil},
+		{"add past max", func() (Money, error) { return usd(math.MaxInt64).Add(usd(1)) }, Money{}, ErrAmountOverflow},
+		{"add past min", func() (Money, error) { return usd(math.MinInt64).Add(usd(-1)) }, Money{}, ErrAmountOverflow},
+		{"add currencies", func() (Money, error) { return usd(1).Add(NewMoney(1, "EUR")) }, Money{}, ErrCurrencyMismatch},
+		{"sub", func() (Money, error) { return usd(150).Sub(usd(250)) }, usd(-100), nil},
+		{"sub to min", func() (Money, error) { return usd(math.MinInt64 + 1).Sub(usd(1)) }, usd(math.MinInt64), nil},
+		{"sub past min", func() (Money, error) { return usd(math.MinInt64).Sub(usd(1)) }, Money{}, ErrAmountOverflow},
+		{"sub past max", func() (Money, error) { return usd(math.MaxInt64).Sub(usd(-1)) }, Money{}, ErrAmountOverflow},
+		{"sub min", func() (Money, error) { return usd(0).Sub(usd(math.MinInt64)) }, Money{}, ErrAmountOverflow},
+		{"sub min from negative", func() (Money, error) { return usd(-1).Sub(usd(math.MinInt64)) }, Money{}, ErrAmountOverflow},
+		{"sub currencies", func() (Money, error) { return usd(1).Sub(NewMoney(1, "EUR")) }, Money{}, ErrCurrencyMismatch},
+		{"mul", func() (Money, error) { return usd(150).Mul(3) }, usd(450), nil},
+		{"mul by zero", func() (Money, error) { return usd(math.MinInt64).Mul(0) }, usd(0), nil},
+		{"mul a negative", func() (Money, error) { return usd(-150).Mul(-3) }, usd(450), nil},
+		{"mul past max", func() (Money, error) { return usd(math.MaxInt64/2 + 1).Mul(2) }, Money{}, ErrAmountOverflow},
+		{"mul past min", func() (Money, error) { return usd(math.MinInt64/2 - 1).Mul(2) }, Money{}, ErrAmountOverflow},
+		{"mul to min", func() (Money, error) { return usd(math.MinInt64 / 2).Mul(2) }, usd(math.MinInt64), nil},
+		{"mul -1 by min", func() (Money, error) { return usd(-1).Mul(math.MinInt64) }, Money{}, ErrAmountOverflow},
+		{"mul min by -1", func() (Money, error) { return usd(math.MinInt64).Mul(-1) }, Money{}, ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		got, err := tt.op()
+		if !errors.Is(err, tt.err) || (err == nil && got != tt.want) {
+			t.Errorf("%s = %v, %v, want %v, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+
+	if _, err := usd(1).Cmp(NewMoney(1, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp across currencies: %v, want ErrCurrencyMismatch", err)
+	}
+	if c, err := usd(math.MinInt64).Cmp(usd(math.MaxInt64)); c != -1 || err != nil {
+		t.Errorf("Cmp(min, max) = %d, %v", c, err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	b, err := json.Marshal(NewMoney(-1234, "USD"))
+	if err != nil || string(b) != `{"amount":"-12.34","currency":"USD"}` {
+		t.Errorf("Marshal = %s, %v", b, err)
+	}
+	var m Money
+	for body, want := range map[string]error{
+		`{"amount":"12.34","currency":"USD"}`:  nil,
+		`{"amount":12.34,"currency":"USD"}`:    ErrInvalidAmount,
+		`{"amount":"12.345","currency":"USD"}`: ErrTooPrecise,
+		`{"amount":"12","currency":"usd"}`:     ErrUnknownCurrency,
+	} {
+		if err := json.Unmarshal([]byte(body), &m); !errors.Is(err, want) {
+			t.Errorf("Unmarshal %s: %v, want %v", body, err, want)
+		}
+	}
+}
@@ -1,18 +1,36 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type Product struct{
 
-	ID uuid.UUID `gorm:"type:uuid;primary_key;"`
+	ID uuid.UUID `gorm:"type:char(36);primaryKey"`
 	
 	Name string `json:"name" gorm:"type:varchar(100);not null"`
 
-	Price float64 `json:"price" gorm:"type:decimal;not null"`
+	Price Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
 
 	Quantity int `json:"quantity" gorm:"type:integer;not null"`
 
-	UserID uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:char(36);not null"`
+
+	// Version is incremented on every write and served as the ETag
+	Version int64 `json:"version" gorm:"not null;default:1"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+
+	CreatedBy *uuid.UUID `json:"created_by" gorm:"type:char(36)"`
+
+	UpdatedBy *uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 
 }
 
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the server side record of an issued refresh token, only
+// the SHA-256 of the token itself is stored
+type RefreshToken struct{
+
+	ID uuid.UUID `gorm:"type:char(36);primaryKey"`
+
+	UserID uuid.UUID `gorm:"type:char(36);not null;index"`
+
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex;not null"`
+
+	ExpiresAt time.Time `gorm:"not null"`
+
+	RevokedAt *time.Time
+
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles a user can hold
+const (
+	RoleAdmin    = "admin"
+	RoleSeller   = "seller"
+	RoleCustomer = "customer"
+)
+
+var Roles = []string{RoleAdmin, RoleSeller, RoleCustomer}
+
+// Permissions checked by the routes
+const (
+	PermUsersRead      = "users:read"
+	PermUsersCreate    = "users:create"
+	PermUsersUpdate    = "users:update"
+	PermUsersDelete    = "users:delete"
+	PermProductsRead   = "products:read"
+	PermProductsCreate = "products:create"
+	PermProductsUpdate = "products:update"
+	PermProductsDelete = "products:delete"
+	PermRBACManage     = "rbac:manage"
+	PermDataPurge      = "data:purge"
+)
+
+var Permissions = []string{
+	PermUsersRead, PermUsersCreate, PermUsersUpdate, PermUsersDelete,
+	PermProductsRead, PermProductsCreate, PermProductsUpdate, PermProductsDelete,
+	PermRBACManage, PermDataPurge,
+}
+
+func IsRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func IsPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermission is one row of the role-permission table
+type RolePermission struct{
+
+	Role string `json:"role" gorm:"type:varchar(100);primaryKey"`
+
+	Permission string `json:"permission" gorm:"type:varchar(100);primaryKey"`
+
+}
+
+// AccessDenial records a request rejected by the permission check
+type AccessDenial struct{
+
+	ID uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+
+	UserID uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+
+	Role string `json:"role" gorm:"type:varchar(100)"`
+
+	Permission string `json:"permission" gorm:"type:varchar(100);not null"`
+
+	Method string `json:"method" gorm:"type:varchar(10);not null"`
+
+	Path string `json:"path" gorm:"type:varchar(255);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+}
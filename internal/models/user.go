@@ -1,11 +1,17 @@
 package model
 
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type User struct{
 
-	ID uuid.UUID `gorm:"type:uuid;primary_key;"`
+	ID uuid.UUID `gorm:"type:char(36);primaryKey"`
 	
 	FirstName string `json:"first_name" gorm:"type:varchar(100);not null"`
 
@@ -13,11 +19,37 @@ type User struct{
 
 	Email string `json:"email" gorm:"type:varchar(100);unique;not null"`
 
-	Password string `json:"password" gorm:"type:varchar(100);not null"`
+	// Password holds the argon2id hash once persisted; it is accepted on
+	// input but never written back out, see MarshalJSON
+	Password string `json:"password" gorm:"type:varchar(255);not null"`
 
 	Role string `json:"role" gorm:"type:varchar(100);not null"`
 
 	Product []Product `json:"product" gorm:"foreignKey:UserID"`
 
+	// Version is incremented on every write and served as the ETag
+	Version int64 `json:"version" gorm:"not null;default:1"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+
+	CreatedBy *uuid.UUID `json:"created_by" gorm:"type:char(36)"`
+
+	UpdatedBy *uuid.UUID `json:"updated_by" gorm:"type:char(36)"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+}
+
+// userJSON has the same fields as User but none of its methods
+type userJSON User
+
+// MarshalJSON drops the password so it never appears in a response
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		userJSON
+		Password string `json:"password,omitempty"`
+	}{userJSON: userJSON(u)})
 }
 
@@ -0,0 +1,374 @@
+// Package openapi models an OpenAPI 3.1 document and derives JSON Schemas
+// for its components from Go types, reading the json and binding tags the
+// way encoding/json and the validator do.
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps a lower case HTTP method to its operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []SecurityRequirement `json:"security"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+// SecurityRequirement names security schemes, an empty one allows anonymous
+// access
+type SecurityRequirement map[string][]string
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is the subset of JSON Schema 2020-12 used here. Type is a string,
+// or a list of them for nullable values.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Examples             []any              `json:"examples,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	ContentSchema        *Schema            `json:"contentSchema,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+}
+
+// Ref refers to the component schema called name
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// Nullable allows null besides the values of s
+func Nullable(s *Schema) *Schema {
+	if t, ok := s.Type.(string); ok && s.Ref == "" {
+		n := *s
+		n.Type = []string{t, "null"}
+		return &n
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Generator turns Go types into schemas, collecting every named struct as a
+// component. Types whose JSON encoding differs from their fields are given
+// to Define or Adjust.
+type Generator struct {
+	schemas  map[string]*Schema
+	defined  map[reflect.Type]*Schema
+	adjust   map[reflect.Type]func(*Schema)
+	rules    map[string]func(*Schema, string)
+	building map[reflect.Type]bool
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas:  make(map[string]*Schema),
+		defined:  make(map[reflect.Type]*Schema),
+		adjust:   make(map[reflect.Type]func(*Schema)),
+		rules:    make(map[string]func(*Schema, string)),
+		building: make(map[reflect.Type]bool),
+	}
+}
+
+// Define uses s for every value of the type of v
+func (g *Generator) Define(v any, s *Schema) {
+	g.defined[reflect.TypeOf(v)] = s
+}
+
+// Component names s in the components and refers to it for every value of
+// the type of v
+func (g *Generator) Component(name string, v any, s *Schema) {
+	g.schemas[name] = s
+	g.defined[reflect.TypeOf(v)] = Ref(name)
+}
+
+// Adjust lets fn change the schema generated for the struct type of v
+func (g *Generator) Adjust(v any, fn func(*Schema)) {
+	g.adjust[reflect.TypeOf(v)] = fn
+}
+
+// Rule applies a binding rule to the schema of the field carrying it, param
+// is what follows the = of the rule
+func (g *Generator) Rule(name string, fn func(s *Schema, param string)) {
+	g.rules[name] = fn
+}
+
+// Schemas returns the component schemas generated so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of v's type, a reference for named structs
+func (g *Generator) Schema(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if s, ok := g.defined[t]; ok {
+		return s
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return Nullable(g.schema(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := componentName(t)
+		if _, ok := g.schemas[name]; !ok && !g.building[t] {
+			g.building[t] = true
+			g.schemas[name] = g.object(t)
+			delete(g.building, t)
+		}
+		return Ref(name)
+	}
+	// interfaces and anything else can hold any JSON value
+	return &Schema{}
+}
+
+// object builds the schema of a struct from its exported fields
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	sort.Strings(s.Required)
+	if fn, ok := g.adjust[t]; ok {
+		fn(s)
+	}
+	return s
+}
+
+func (g *Generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a name of their own are flattened, as
+		// encoding/json does
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		binding, hasBinding := f.Tag.Lookup("binding")
+		ft := f.Type
+		// a required pointer only tells a missing member from a zero one
+		if ft.Kind() == reflect.Pointer && hasRule(binding, "required") {
+			ft = ft.Elem()
+		}
+		field := g.schema(ft)
+		required := !strings.Contains(opts, "omitempty")
+		if hasBinding {
+			required = false
+			field = g.applyRules(field, binding, &required)
+		}
+		s.Properties[name] = field
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyRules copies field before narrowing it with the binding rules, it may
+// be shared with other fields
+func (g *Generator) applyRules(field *Schema, binding string, required *bool) *Schema {
+	narrowed := *field
+	changed := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			*required = true
+			continue
+		case "omitempty", "dive":
+			continue
+		}
+		if fn, ok := g.rules[name]; ok {
+			fn(&narrowed, param)
+			changed = true
+		} else if fn, ok := builtinRules[name]; ok {
+			fn(&narrowed, param)
+			changed = true
+		}
+	}
+	if !changed {
+		return field
+	}
+	// narrowing a nullable reference has to go through allOf like keywords,
+	// keep the reference and note nothing for it
+	if narrowed.Ref != "" || narrowed.AnyOf != nil {
+		return field
+	}
+	return &narrowed
+}
+
+var builtinRules = map[string]func(*Schema, string){
+	"email": func(s *Schema, _ string) { s.Format = "email" },
+	"uuid":  func(s *Schema, _ string) { s.Format = "uuid" },
+	"max":   func(s *Schema, p string) { bound(s, p, &s.MaxLength, &s.Maximum) },
+	"min":   func(s *Schema, p string) { bound(s, p, &s.MinLength, &s.Minimum) },
+	"gte":   func(s *Schema, p string) { bound(s, p, &s.MinLength, &s.Minimum) },
+	"lte":   func(s *Schema, p string) { bound(s, p, &s.MaxLength, &s.Maximum) },
+	"gt": func(s *Schema, p string) {
+		if n, err := strconv.ParseFloat(p, 64); err == nil {
+			s.ExclusiveMinimum = &n
+		}
+	},
+	"oneof": func(s *Schema, p string) {
+		for _, v := range strings.Fields(p) {
+			s.Enum = append(s.Enum, v)
+		}
+	},
+}
+
+// bound sets a length for strings and a value bound for numbers, the way the
+// validator reads max, min, gte and lte
+func bound(s *Schema, param string, length **int, value **float64) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	if s.Type == "string" {
+		l := int(n)
+		*length = &l
+		return
+	}
+	*value = &n
+}
+
+// componentName is the type name with its first letter upper cased, request
+// types are unexported
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func hasRule(binding, name string) bool {
+	for _, rule := range strings.Split(binding, ",") {
+		if rule == name || strings.HasPrefix(rule, name+"=") {
+			return true
+		}
+	}
+	return false
+}
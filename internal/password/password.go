@@ -0,0 +1,114 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id parameters used for new hashes
+const (
+	memory     uint32 = 64 * 1024
+	iterations uint32 = 3
+	threads    uint8  = 2
+	saltLen           = 16
+	keyLen     uint32 = 32
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+type params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// Hash returns an encoded argon2id hash in the PHC string format, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func Hash(plain string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, iterations, memory, threads, keyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, memory, iterations, threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether plain matches the encoded hash. A stored value that
+// is not a PHC string is a plain-text password from before passwords were
+// hashed; it is compared as is and NeedsRehash reports it for replacement.
+func Verify(plain, encoded string) (bool, error) {
+	if isLegacy(encoded) {
+		// compare digests so that the time taken does not depend on the
+		// length of the stored password
+		a, b := sha256.Sum256([]byte(plain)), sha256.Sum256([]byte(encoded))
+		return subtle.ConstantTimeCompare(a[:], b[:]) == 1, nil
+	}
+
+	p, err := decode(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(plain), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+
+	return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+}
+
+// NeedsRehash reports whether the encoded hash was made with weaker
+// parameters than the current ones and should be replaced on next login
+func NeedsRehash(encoded string) bool {
+	p, err := decode(encoded)
+	if err != nil {
+		return true
+	}
+	return p.memory < memory || p.time < iterations || p.threads < threads || uint32(len(p.key)) < keyLen
+}
+
+// isLegacy tells a plain-text password apart from a hash, every PHC string
+// starts with $
+func isLegacy(encoded string) bool {
+	return encoded != "" && !strings.HasPrefix(encoded, "$")
+}
+
+func decode(encoded string) (params, error) {
+	var p params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, ErrInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, ErrInvalidHash
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, ErrInvalidHash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return p, ErrInvalidHash
+	}
+
+	return p, nil
+}
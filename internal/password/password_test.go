@@ -0,0 +1,110 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashVerify(t *testing.T) {
+	hash, err := Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("hash %q is not an argon2id PHC string with the current parameters", hash)
+	}
+	if ok, err := Verify("correct horse", hash); !ok || err != nil {
+		t.Errorf("Verify with the right password = %v, %v", ok, err)
+	}
+	if ok, err := Verify("correct horse ", hash); ok || err != nil {
+		t.Errorf("Verify with a wrong password = %v, %v", ok, err)
+	}
+	if NeedsRehash(hash) {
+		t.Error("a fresh hash needs a rehash")
+	}
+
+	other, _ := Hash("correct horse")
+	if other == hash {
+		t.Error("two hashes of the same password share a salt")
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	hash, err := Hash("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+	with := func(i int, value string) string {
+		p := append([]string(nil), parts...)
+		p[i] = value
+		return strings.Join(p, "$")
+	}
+	for name, encoded := range map[string]string{
+		"empty":          "",
+		"algorithm":      with(1, "argon2i"),
+		"version":        with(2, "v=16"),
+		"parameters":     with(3, "m=65536"),
+		"salt":           with(4, "not base64!"),
+		"key":            with(5, "not base64!"),
+		"empty key":      with(5, ""),
+		"too few fields": strings.Join(parts[:5], "$"),
+	} {
+		if ok, err := Verify("pw", encoded); ok || !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%s: Verify = %v, %v, want ErrInvalidHash", name, ok, err)
+		}
+		if !NeedsRehash(encoded) {
+			t.Errorf("%s: a malformed hash does not need a rehash", name)
+		}
+	}
+}
+
+// TestNeedsRehash checks that hashes made with weaker parameters than the
+// current ones are reported, and still verify until they are replaced
+func TestNeedsRehash(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	hashWith := func(m, t uint32, p uint8, keyLen uint32) string {
+		key := argon2.IDKey([]byte("pw"), salt, t, m, p, keyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, m, t, p,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	}
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+	}{
+		{"current", hashWith(memory, iterations, threads, keyLen), false},
+		{"less memory", hashWith(memory/2, iterations, threads, keyLen), true},
+		{"fewer iterations", hashWith(memory, iterations-1, threads, keyLen), true},
+		{"fewer threads", hashWith(memory, iterations, threads-1, keyLen), true},
+		{"shorter key", hashWith(memory, iterations, threads, keyLen/2), true},
+		{"stronger", hashWith(memory*2, iterations+1, threads, keyLen), false},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash); got != tt.rehash {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.rehash)
+		}
+		if ok, err := Verify("pw", tt.hash); !ok || err != nil {
+			t.Errorf("%s: Verify = %v, %v", tt.name, ok, err)
+		}
+	}
+}
+
+func TestVerifyLegacyPlainText(t *testing.T) {
+	if ok, err := Verify("password123", "password123"); !ok || err != nil {
+		t.Errorf("Verify against the stored plain text = %v, %v", ok, err)
+	}
+	for _, plain := range []string{"password12", "password1234", "Password123", ""} {
+		if ok, err := Verify(plain, "password123"); ok || err != nil {
+			t.Errorf("Verify(%q) against plain text password123 = %v, %v", plain, ok, err)
+		}
+	}
+	if !NeedsRehash("password123") {
+		t.Error("a plain-text password does not need a rehash")
+	}
+}
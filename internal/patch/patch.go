@@ -0,0 +1,315 @@
+// Package patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch
+// documents to decoded JSON values (maps, slices and scalars as produced by
+// encoding/json).
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrTestFailed   = errors.New("patch test operation failed")
+	ErrPathNotFound = errors.New("patch path not found")
+)
+
+// Decode turns v into its generic JSON form
+func Decode(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	err = json.Unmarshal(b, &doc)
+	return doc, err
+}
+
+// Encode stores a generic JSON document into dest
+func Encode(doc any, dest any) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dest)
+}
+
+// Merge applies a merge patch to doc as described in RFC 7396, null removes a
+// member and objects are merged recursively
+func Merge(doc, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	target, ok := doc.(map[string]any)
+	if !ok {
+		target = map[string]any{}
+	} else {
+		target = copyObject(target)
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		target[key] = Merge(target[key], value)
+	}
+	return target
+}
+
+// Operation is one step of a JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply runs the operations in order as described in RFC 6902. The patch is
+// atomic, on error doc is left untouched.
+func Apply(doc any, ops []Operation) (any, error) {
+	var err error
+	doc, err = Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var value any
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) has no value", ErrInvalidPatch, i, op.Op)
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+			}
+		}
+
+		switch op.Op {
+		case "add":
+			doc, err = add(doc, op.Path, value)
+		case "remove":
+			doc, _, err = remove(doc, op.Path)
+		case "replace":
+			if doc, _, err = remove(doc, op.Path); err == nil {
+				doc, err = add(doc, op.Path, value)
+			}
+		case "move":
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("%w: operation %d moves %s into itself", ErrInvalidPatch, i, op.From)
+			}
+			var moved any
+			if doc, moved, err = remove(doc, op.From); err == nil {
+				doc, err = add(doc, op.Path, moved)
+			}
+		case "copy":
+			var copied any
+			if copied, err = get(doc, op.From); err == nil {
+				if copied, err = Decode(copied); err == nil {
+					doc, err = add(doc, op.Path, copied)
+				}
+			}
+		case "test":
+			var actual any
+			if actual, err = get(doc, op.Path); err == nil && !reflect.DeepEqual(actual, value) {
+				err = fmt.Errorf("%w: %s", ErrTestFailed, op.Path)
+			}
+		default:
+			err = fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// ChangedMembers lists the top level members that differ between two objects
+func ChangedMembers(before, after any) []string {
+	from, _ := before.(map[string]any)
+	to, _ := after.(map[string]any)
+
+	var changed []string
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPatch, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPatch, token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: index %d", ErrPathNotFound, i)
+	}
+	return i, nil
+}
+
+func get(doc any, path string) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	}
+	return doc, nil
+}
+
+// update replaces the container at the parent of path with the result of fn
+// applied to it and the last token, rebuilding the path back up to the root
+func update(doc any, path string, fn func(parent any, token string) (any, error)) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return fn(nil, "")
+	}
+	return updateAt(doc, tokens, path, fn)
+}
+
+func updateAt(node any, tokens []string, path string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return fn(node, tokens[0])
+	}
+	child, err := get(node, "/"+escape(tokens[0]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+	child, err = updateAt(child, tokens[1:], path, fn)
+	if err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		n[tokens[0]] = child
+	case []any:
+		i, _ := arrayIndex(tokens[0], len(n), false)
+		n[i] = child
+	}
+	return node, nil
+}
+
+func add(doc any, path string, value any) (any, error) {
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case nil:
+			if path == "" {
+				return value, nil
+			}
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	})
+}
+
+func remove(doc any, path string) (any, any, error) {
+	var removed any
+	if path == "" {
+		return nil, doc, nil
+	}
+	doc, err := update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	})
+	return doc, removed, err
+}
+
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func copyObject(obj map[string]any) map[string]any {
+	c := make(map[string]any, len(obj))
+	for k, v := range obj {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,172 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	return v
+}
+
+func TestApply(t *testing.T) {
+	const doc = `{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`
+	tests := []struct {
+		name string
+		ops  string
+		want string
+		err  error
+	}{
+		// add
+		{"add member", `[{"op":"add","path":"/quantity","value":3}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2,"quantity":3}`, nil},
+		{"add replaces member", `[{"op":"add","path":"/name","value":"Table"}]`,
+			`{"name":"Table","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"add nested", `[{"op":"add","path":"/price/amount","value":"2.00"}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"2.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"add inserts into array", `[{"op":"add","path":"/tags/1","value":"x"}]`,
+			`{"name":"Chair","tags":["a","x","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"add at array length", `[{"op":"add","path":"/tags/3","value":"x"}]`,
+			`{"name":"Chair","tags":["a","b","c","x"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"add appends with -", `[{"op":"add","path":"/tags/-","value":"x"}]`,
+			`{"name":"Chair","tags":["a","b","c","x"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"add past array end", `[{"op":"add","path":"/tags/4","value":"x"}]`, "", ErrPathNotFound},
+		{"add under missing parent", `[{"op":"add","path":"/missing/x","value":1}]`, "", ErrPathNotFound},
+		{"add without value", `[{"op":"add","path":"/x"}]`, "", ErrInvalidPatch},
+		{"add whole document", `[{"op":"add","path":"","value":{"x":1}}]`, `{"x":1}`, nil},
+
+		// remove
+		{"remove member", `[{"op":"remove","path":"/price"}]`,
+			`{"name":"Chair","tags":["a","b","c"],"a/b":1,"m~n":2}`, nil},
+		{"remove array element", `[{"op":"remove","path":"/tags/0"}]`,
+			`{"name":"Chair","tags":["b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"remove missing member", `[{"op":"remove","path":"/missing"}]`, "", ErrPathNotFound},
+		{"remove past array end", `[{"op":"remove","path":"/tags/3"}]`, "", ErrPathNotFound},
+		{"remove with -", `[{"op":"remove","path":"/tags/-"}]`, "", ErrInvalidPatch},
+
+		// replace
+		{"replace member", `[{"op":"replace","path":"/name","value":"Table"}]`,
+			`{"name":"Table","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"replace array element", `[{"op":"replace","path":"/tags/2","value":"z"}]`,
+			`{"name":"Chair","tags":["a","b","z"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"replace missing member", `[{"op":"replace","path":"/missing","value":1}]`, "", ErrPathNotFound},
+		{"replace with null", `[{"op":"replace","path":"/name","value":null}]`,
+			`{"name":null,"tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+
+		// move
+		{"move member", `[{"op":"move","from":"/name","path":"/title"}]`,
+			`{"title":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"move within array", `[{"op":"move","from":"/tags/0","path":"/tags/-"}]`,
+			`{"name":"Chair","tags":["b","c","a"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"move onto itself", `[{"op":"move","from":"/price","path":"/price"}]`, doc, nil},
+		{"move into itself", `[{"op":"move","from":"/price","path":"/price/inner"}]`, "", ErrInvalidPatch},
+		{"move missing member", `[{"op":"move","from":"/missing","path":"/x"}]`, "", ErrPathNotFound},
+
+		// copy
+		{"copy member", `[{"op":"copy","from":"/price","path":"/list_price"}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"list_price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"copy is not shared", `[{"op":"copy","from":"/price","path":"/list_price"},{"op":"replace","path":"/list_price/amount","value":"9.00"}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"list_price":{"amount":"9.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"copy missing member", `[{"op":"copy","from":"/missing","path":"/x"}]`, "", ErrPathNotFound},
+
+		// test
+		{"test passes", `[{"op":"test","path":"/price","value":{"currency":"USD","amount":"1.00"}}]`, doc, nil},
+		{"test fails", `[{"op":"test","path":"/name","value":"Table"}]`, "", ErrTestFailed},
+		{"test number type", `[{"op":"test","path":"/a~1b","value":"1"}]`, "", ErrTestFailed},
+		{"test missing member", `[{"op":"test","path":"/missing","value":1}]`, "", ErrPathNotFound},
+
+		// pointers
+		{"escaped slash", `[{"op":"replace","path":"/a~1b","value":10}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":10,"m~n":2}`, nil},
+		{"escaped tilde", `[{"op":"remove","path":"/m~0n"}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1}`, nil},
+		{"~01 is ~1", `[{"op":"add","path":"/~01","value":true}]`,
+			`{"name":"Chair","tags":["a","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2,"~1":true}`, nil},
+		{"leading zero index", `[{"op":"replace","path":"/tags/01","value":"x"}]`, "", ErrInvalidPatch},
+		{"zero index", `[{"op":"replace","path":"/tags/0","value":"x"}]`,
+			`{"name":"Chair","tags":["x","b","c"],"price":{"amount":"1.00","currency":"USD"},"a/b":1,"m~n":2}`, nil},
+		{"negative index", `[{"op":"remove","path":"/tags/-1"}]`, "", ErrInvalidPatch},
+		{"index into scalar", `[{"op":"add","path":"/name/0","value":"x"}]`, "", ErrPathNotFound},
+		{"relative path", `[{"op":"remove","path":"name"}]`, "", ErrInvalidPatch},
+		{"unknown op", `[{"op":"frobnicate","path":"/name"}]`, "", ErrInvalidPatch},
+
+		// atomicity
+		{"failure undoes earlier ops", `[{"op":"replace","path":"/name","value":"Table"},{"op":"remove","path":"/tags/0"},{"op":"test","path":"/name","value":"Chair"}]`, "", ErrTestFailed},
+	}
+	for _, tt := range tests {
+		original := decodeJSON(t, doc)
+		input := decodeJSON(t, doc)
+		var ops []Operation
+		if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got, err := Apply(input, ops)
+		if !reflect.DeepEqual(input, original) {
+			t.Errorf("%s: Apply changed its input to %v", tt.name, input)
+		}
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) || got != nil {
+				t.Errorf("%s: Apply = %v, %v, want %v", tt.name, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"set member", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{"replace member", `{"a":1}`, `{"a":"x"}`, `{"a":"x"}`},
+		{"null deletes", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"null on missing member", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"nested merge", `{"p":{"x":1,"y":2}}`, `{"p":{"y":null,"z":3}}`, `{"p":{"x":1,"z":3}}`},
+		{"nested null never stored", `{}`, `{"p":{"x":null}}`, `{"p":{}}`},
+		{"arrays are replaced", `{"t":[1,2,3]}`, `{"t":[4]}`, `{"t":[4]}`},
+		{"object replaces scalar", `{"a":1}`, `{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		{"scalar replaces object", `{"a":{"b":1}}`, `{"a":1}`, `{"a":1}`},
+		{"non-object patch replaces", `{"a":1}`, `[1,2]`, `[1,2]`},
+		{"patch onto non-object", `[1]`, `{"a":1}`, `{"a":1}`},
+		{"empty patch", `{"a":1}`, `{}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		doc := decodeJSON(t, tt.doc)
+		got := Merge(doc, decodeJSON(t, tt.patch))
+		if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, want)
+		}
+		if !reflect.DeepEqual(doc, decodeJSON(t, tt.doc)) {
+			t.Errorf("%s: Merge changed its input to %v", tt.name, doc)
+		}
+	}
+}
+
+func TestChangedMembers(t *testing.T) {
+	before := decodeJSON(t, `{"a":1,"b":{"c":2},"d":3}`)
+	after := decodeJSON(t, `{"a":1,"b":{"c":4},"e":5}`)
+	got := ChangedMembers(before, after)
+	sort.Strings(got)
+	if want := []string{"b", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedMembers = %v, want %v", got, want)
+	}
+}
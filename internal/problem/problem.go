@@ -0,0 +1,99 @@
+// Package problem writes errors as RFC 9457 application/problem+json
+// documents. Every problem carries a stable code, also used in its type URI.
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/services"
+)
+
+const ContentType = "application/problem+json"
+
+// TypePrefix is prepended to the code to form the problem type URI
+const TypePrefix = "urn:homework1:problem:"
+
+type Problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Code     string                `json:"code"`
+	Errors   []services.FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   TypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Invalid is a validation problem listing the offending fields
+func Invalid(status int, errs []services.FieldError) Problem {
+	p := New(status, "validation_failed", "the request is invalid")
+	p.Errors = errs
+	return p
+}
+
+var kindStatus = map[services.ErrorKind]int{
+	services.KindNotFound:     http.StatusNotFound,
+	services.KindConflict:     http.StatusConflict,
+	services.KindValidation:   http.StatusUnprocessableEntity,
+	services.KindForbidden:    http.StatusForbidden,
+	services.KindUnauthorized: http.StatusUnauthorized,
+	services.KindPrecondition: http.StatusPreconditionFailed,
+	services.KindBadRequest:   http.StatusBadRequest,
+}
+
+// FromError maps a domain error to its problem. Anything else is an internal
+// error whose message is not exposed.
+func FromError(err error) Problem {
+	var domain *services.Error
+	if errors.As(err, &domain) {
+		status, ok := kindStatus[domain.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		p := New(status, domain.Code, domain.Message)
+		p.Errors = domain.Fields
+		return p
+	}
+	return New(http.StatusInternalServerError, "internal_error", "an unexpected error occurred")
+}
+
+// Body is the problem for an error reading the request body, a body cut off
+// by http.MaxBytesReader is too large
+func Body(err error) Problem {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return New(http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("the body may be at most %d bytes", tooLarge.Limit))
+	}
+	return New(http.StatusBadRequest, "malformed_body", err.Error())
+}
+
+// Write sends p and aborts the request
+func Write(c *gin.Context, p Problem) {
+	p.Instance = c.Request.URL.Path
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// Error sends the problem for err, internal errors are logged since the
+// response does not show them
+func Error(c *gin.Context, err error) {
+	p := FromError(err)
+	if p.Status == http.StatusInternalServerError {
+		slog.ErrorContext(c.Request.Context(), "internal error", "error", err)
+	}
+	_ = c.Error(err)
+	Write(c, p)
+}
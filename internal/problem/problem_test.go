@@ -0,0 +1,96 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/services"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+		{services.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+		{services.ErrInvalidSearch, http.StatusBadRequest, "invalid_search"},
+		{services.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+		{services.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token"},
+		{services.ErrNotProductOwner, http.StatusForbidden, "not_product_owner"},
+		{services.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+		{services.ErrProductNotFound, http.StatusNotFound, "product_not_found"},
+		{services.ErrNoVersionAsOf, http.StatusNotFound, "no_version_at_time"},
+		{services.ErrEmailTaken, http.StatusConflict, "email_taken"},
+		{services.ErrOwnerDeleted, http.StatusConflict, "owner_deleted"},
+		{services.ErrUserHasProducts, http.StatusConflict, "user_has_products"},
+		{services.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+		{services.ErrInvalidPrice, http.StatusUnprocessableEntity, "invalid_price"},
+		{fmt.Errorf("listing products: %w", services.ErrInvalidCursor), http.StatusBadRequest, "invalid_cursor"},
+		{&services.Error{Kind: "unknown", Code: "odd", Message: "odd"}, http.StatusInternalServerError, "odd"},
+		{errors.New("dial tcp 10.0.0.1:5432: connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		p := FromError(tt.err)
+		if p.Status != tt.status || p.Code != tt.code {
+			t.Errorf("%v: %d %s, want %d %s", tt.err, p.Status, p.Code, tt.status, tt.code)
+		}
+		if p.Type != TypePrefix+tt.code || p.Title != http.StatusText(tt.status) {
+			t.Errorf("%v: type %q title %q", tt.err, p.Type, p.Title)
+		}
+	}
+
+	if p := FromError(errors.New("secret dsn")); strings.Contains(p.Detail, "secret") {
+		t.Errorf("an internal error shows its message: %q", p.Detail)
+	}
+	fields := []services.FieldError{{Field: "price.amount", Code: "precision", Message: "too precise"}}
+	p := FromError(services.Validation("invalid_price", "bad price", fields...))
+	if len(p.Errors) != 1 || p.Errors[0] != fields[0] || p.Detail != "bad price" {
+		t.Errorf("validation problem %+v, want its fields and message", p)
+	}
+}
+
+func TestBody(t *testing.T) {
+	if p := Body(&http.MaxBytesError{Limit: 10}); p.Status != http.StatusRequestEntityTooLarge || p.Code != "body_too_large" {
+		t.Errorf("too large body: %d %s", p.Status, p.Code)
+	}
+	if p := Body(fmt.Errorf("reading: %w", io.ErrUnexpectedEOF)); p.Status != http.StatusBadRequest || p.Code != "malformed_body" {
+		t.Errorf("malformed body: %d %s", p.Status, p.Code)
+	}
+}
+
+func TestError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	called := false
+	router.GET("/products", func(c *gin.Context) {
+		Error(c, services.ErrInvalidCursor)
+	}, func(*gin.Context) { called = true })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?cursor=x", nil))
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != ContentType {
+		t.Errorf("%d %s, want 400 %s", w.Code, w.Header().Get("Content-Type"), ContentType)
+	}
+	var got Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := New(http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+	want.Instance = "/products"
+	if got.Type != want.Type || got.Status != want.Status || got.Code != want.Code || got.Detail != want.Detail || got.Instance != want.Instance {
+		t.Errorf("problem %+v, want %+v", got, want)
+	}
+	if called {
+		t.Error("the handler chain went on after the problem")
+	}
+}
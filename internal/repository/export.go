@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+// exportBatchSize is the number of rows an export loads at a time
+const exportBatchSize = 500
+
+// Export calls fn with the products matching filter, a batch at a time in
+// ID order, so the result never has to fit in memory
+func (r *productRepository) Export(ctx context.Context, filter ProductFilter, fn func([]model.Product) error) error {
+	var batch []model.Product
+	query := filter.apply(r.db.WithContext(ctx).Model(&model.Product{}))
+	return query.FindInBatches(&batch, exportBatchSize, func(*gorm.DB, int) error {
+		return fn(batch)
+	}).Error
+}
+
+// Export calls fn with the users matching filter like the product export.
+// Passwords are not even loaded.
+func (r *userRepository) Export(ctx context.Context, filter UserFilter, fn func([]model.User) error) error {
+	var batch []model.User
+	query := filter.apply(r.db.WithContext(ctx).Model(&model.User{}).Omit("password"))
+	return query.FindInBatches(&batch, exportBatchSize, func(*gorm.DB, int) error {
+		return fn(batch)
+	}).Error
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeRange bounds created_at and updated_at, nil fields are ignored.
+// After is inclusive and Before exclusive.
+type TimeRange struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+func (t TimeRange) apply(query *gorm.DB) *gorm.DB {
+	bounds := []struct {
+		cond  string
+		value *time.Time
+	}{
+		{"created_at >= ?", t.CreatedAfter},
+		{"created_at < ?", t.CreatedBefore},
+		{"updated_at >= ?", t.UpdatedAfter},
+		{"updated_at < ?", t.UpdatedBefore},
+	}
+	for _, b := range bounds {
+		if b.value != nil {
+			// timestamps are written in UTC, see database.InitDB
+			query = query.Where(b.cond, b.value.UTC())
+		}
+	}
+	return query
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/audit"
+	"homework1/internal/models"
+)
+
+// History tables, one per tracked model
+const (
+	productHistoryTable = "product_history"
+	userHistoryTable    = "user_history"
+)
+
+// ErrNoHistory is returned by AsOf when the row did not exist at that time
+var ErrNoHistory = errors.New("no version exists at that time")
+
+// fieldChange is one entry of a history diff
+type fieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// snapshot turns a model into its JSON fields, relations are left out since
+// they have their own history
+func snapshot(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "product")
+	return fields, nil
+}
+
+// recordChange writes one history entry for the change from before to after,
+// either of which may be nil for an insert or a purge
+func recordChange(tx *gorm.DB, table, operation string, id uuid.UUID, before, after any) error {
+	var from, to map[string]any
+	var err error
+	if before != nil {
+		if from, err = snapshot(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if to, err = snapshot(after); err != nil {
+			return err
+		}
+	}
+
+	diff := map[string]fieldChange{}
+	for key, value := range to {
+		if !reflect.DeepEqual(from[key], value) {
+			diff[key] = fieldChange{From: from[key], To: value}
+		}
+	}
+	for key, old := range from {
+		if _, ok := to[key]; !ok && old != nil {
+			diff[key] = fieldChange{From: old}
+		}
+	}
+
+	state := to
+	if after == nil {
+		state = from
+	}
+	snapshotJSON, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	entry := model.HistoryEntry{
+		ID:        uuid.New(),
+		EntityID:  id,
+		Operation: operation,
+		Snapshot:  string(snapshotJSON),
+		Diff:      string(diffJSON),
+		ChangedAt: tx.NowFunc(),
+	}
+	if actor, ok := audit.ActorFrom(tx.Statement.Context); ok {
+		entry.ActorID = &actor
+	}
+	return tx.Table(table).Create(&entry).Error
+}
+
+// recordEach records a change for every row in before after apply has run,
+// reloading the rows so the entries show what was actually stored
+func recordEach[T any](tx *gorm.DB, table, operation string, before []T, id func(T) uuid.UUID, apply func() error) error {
+	if err := apply(); err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(before))
+	for i, row := range before {
+		ids[i] = id(row)
+	}
+	var after []T
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&after).Error; err != nil {
+		return err
+	}
+	byID := map[uuid.UUID]T{}
+	for _, row := range after {
+		byID[id(row)] = row
+	}
+
+	for _, row := range before {
+		var changed any
+		if updated, ok := byID[id(row)]; ok {
+			changed = updated
+		}
+		if err := recordChange(tx, table, operation, id(row), row, changed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func productID(p model.Product) uuid.UUID { return p.ID }
+
+func userID(u model.User) uuid.UUID { return u.ID }
+
+// history lists the entries of one row, oldest first
+func history(ctx context.Context, db *gorm.DB, table string, id uuid.UUID) ([]model.HistoryEntry, error) {
+	var entries []model.HistoryEntry
+	err := db.WithContext(ctx).Table(table).
+		Where("entity_id = ?", id).
+		Order("changed_at, id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// asOf decodes the newest snapshot of a row taken at or before t into dest
+func asOf(ctx context.Context, db *gorm.DB, table string, id uuid.UUID, t time.Time, dest any) error {
+	var entry model.HistoryEntry
+	err := db.WithContext(ctx).Table(table).
+		Where("entity_id = ? AND changed_at <= ?", id, t.UTC()).
+		Order("changed_at DESC, id DESC").
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || entry.Operation == model.HistoryPurge {
+		return ErrNoHistory
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(entry.Snapshot), dest)
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/audit"
+	"homework1/internal/models"
+)
+
+func TestProductHistory(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	ctx := audit.WithActor(context.Background(), owner.ID)
+	products := NewProductRepository(db)
+
+	product := createTestProduct(t, db, owner.ID)
+	updated := product
+	updated.Name = "Table"
+	if _, err := products.Update(ctx, product.ID, 0, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := products.Delete(ctx, product.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Restore(ctx, product.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := products.History(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{model.HistoryInsert, model.HistoryUpdate, model.HistoryDelete, model.HistoryRestore}
+	if len(entries) != len(want) {
+		t.Fatalf("%d history entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Operation != want[i] || entry.EntityID != product.ID {
+			t.Errorf("entry %d is %s of %v, want %s of %v", i, entry.Operation, entry.EntityID, want[i], product.ID)
+		}
+	}
+	if entries[0].ActorID != nil {
+		t.Errorf("the insert made without an actor records actor %v", *entries[0].ActorID)
+	}
+	if actor := entries[1].ActorID; actor == nil || *actor != owner.ID {
+		t.Errorf("the update records actor %v, want %v", actor, owner.ID)
+	}
+
+	var diff map[string]fieldChange
+	if err := json.Unmarshal([]byte(entries[1].Diff), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if name := diff["name"]; name.From != "Chair" || name.To != "Table" {
+		t.Errorf("name diff %+v, want Chair to Table", name)
+	}
+	for field := range diff {
+		switch field {
+		case "name", "version", "updated_at", "updated_by":
+		default:
+			t.Errorf("the update diff lists unchanged field %s", field)
+		}
+	}
+	var snapshot map[string]any
+	if err := json.Unmarshal([]byte(entries[2].Snapshot), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot["name"] != "Table" || snapshot["deleted_at"] == nil {
+		t.Errorf("delete snapshot %v, want the deleted product", snapshot)
+	}
+
+	if _, err := products.History(ctx, uuid.New()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("History of an unknown product = %v, want ErrRecordNotFound", err)
+	}
+}
+
+// TestHistoryOfUntrackedProduct checks that a product stored before history
+// was recorded has an empty history rather than none
+func TestHistoryOfUntrackedProduct(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	product := model.Product{ID: uuid.New(), Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner.ID}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+	entries, err := NewProductRepository(db).History(context.Background(), product.ID)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("History = %v, %v, want no entries", entries, err)
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := start
+	products := NewProductRepository(db.Session(&gorm.Session{NowFunc: func() time.Time { return clock }}))
+
+	product, err := products.Create(ctx, model.Product{ID: uuid.New(), Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock = start.Add(time.Hour)
+	updated := product
+	updated.Name = "Table"
+	if _, err := products.Update(ctx, product.ID, 0, updated); err != nil {
+		t.Fatal(err)
+	}
+	clock = start.Add(2 * time.Hour)
+	if err := products.Delete(ctx, product.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	clock = start.Add(3 * time.Hour)
+	if _, err := products.Purge(ctx, clock); err != nil {
+		t.Fatal(err)
+	}
+
+	// times in another zone name the same instants
+	east := time.FixedZone("UTC+3", 3*60*60)
+	tests := []struct {
+		name    string
+		at      time.Time
+		want    string
+		deleted bool
+		err     error
+	}{
+		{"before insert", start.Add(-time.Minute), "", false, ErrNoHistory},
+		{"at insert", start, "Chair", false, nil},
+		{"between", start.Add(30 * time.Minute).In(east), "Chair", false, nil},
+		{"at update", start.Add(time.Hour), "Table", false, nil},
+		{"deleted", start.Add(150 * time.Minute).In(east), "Table", true, nil},
+		{"purged", start.Add(4 * time.Hour), "", false, ErrNoHistory},
+	}
+	for _, tt := range tests {
+		got, err := products.AsOf(ctx, product.ID, tt.at)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if got.Name != tt.want || got.DeletedAt.Valid != tt.deleted {
+			t.Errorf("%s: got %q deleted %v, want %q deleted %v", tt.name, got.Name, got.DeletedAt.Valid, tt.want, tt.deleted)
+		}
+	}
+	if _, err := products.AsOf(ctx, uuid.New(), clock); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("AsOf of an unknown product = %v, want ErrNoHistory", err)
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key model.IdempotencyKey) (model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, key model.IdempotencyKey) error
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve stores key unless its scope and key are already taken, in which
+// case the stored record is returned and reserved is false. Expired keys are
+// removed first so they can be reused. Expiry is compared with the time
+// GORM writes, UTC, SQLite compares times as text.
+func (r *idempotencyRepository) Reserve(ctx context.Context, key model.IdempotencyKey) (model.IdempotencyKey, bool, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("expires_at < ?", db.NowFunc()).Delete(&model.IdempotencyKey{}).Error; err != nil {
+		return key, false, err
+	}
+
+	err := db.Create(&key).Error
+	if err == nil {
+		return key, true, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return key, false, err
+	}
+
+	var stored model.IdempotencyKey
+	if err := db.First(&stored, "scope = ? AND idempotency_key = ?", key.Scope, key.Key).Error; err != nil {
+		return key, false, err
+	}
+	return stored, false, nil
+}
+
+// Complete stores the response of a reserved key
+func (r *idempotencyRepository) Complete(ctx context.Context, key model.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&model.IdempotencyKey{ID: key.ID}).
+		Select("status_code", "headers", "body").
+		Updates(&key).Error
+}
+
+// Release drops a reservation so the request can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&model.IdempotencyKey{}, "id = ?", id).Error
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+// TestReserveExpiresInUTC checks expiry on hosts whose zone is not UTC, the
+// stored times are UTC text
+func TestReserveExpiresInUTC(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	for _, offset := range []int{-5, 5} {
+		time.Local = time.FixedZone("test", offset*3600)
+		db := newTestDB(t)
+		repo := NewIdempotencyRepository(db)
+		ctx := context.Background()
+		now := time.Now().UTC()
+		key := func(name string, expiresAt time.Time) model.IdempotencyKey {
+			return model.IdempotencyKey{ID: uuid.New(), Scope: "POST /products", Key: name, Fingerprint: "f", ExpiresAt: expiresAt}
+		}
+
+		for _, k := range []model.IdempotencyKey{key("expired", now.Add(-time.Minute)), key("live", now.Add(time.Minute))} {
+			if _, reserved, err := repo.Reserve(ctx, k); err != nil || !reserved {
+				t.Fatalf("UTC%+d: first Reserve of %s = %v, %v", offset, k.Key, reserved, err)
+			}
+		}
+
+		if _, reserved, err := repo.Reserve(ctx, key("expired", now.Add(time.Hour))); err != nil || !reserved {
+			t.Errorf("UTC%+d: an expired key was not reusable, reserved %v, error %v", offset, reserved, err)
+		}
+		if _, reserved, err := repo.Reserve(ctx, key("live", now.Add(time.Hour))); err != nil || reserved {
+			t.Errorf("UTC%+d: a live key was reserved again, reserved %v, error %v", offset, reserved, err)
+		}
+	}
+}
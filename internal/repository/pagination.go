@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+// Page selects one page of a keyset paginated list. Sort names a whitelisted
+// column, rows are always ordered by id as well so the order is total.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Desc   bool
+}
+
+func (p Page) normalize() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Sort == "" {
+		p.Sort = "id"
+	}
+	return p
+}
+
+// cursor is the decoded form of the opaque next_cursor token, it remembers
+// the sort it was made for so it cannot be replayed against another one
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value any    `json:"v"`
+	ID    string `json:"id"`
+}
+
+// sortField maps a public sort name to its column and reads the cursor value
+// from a row
+type sortField[T any] struct {
+	column string
+	value  func(row T) any
+}
+
+// accepts reports whether a value read from a cursor has the type of the
+// field's values. A forged cursor could otherwise bind a list, an object or a
+// string in place of a number to the keyset condition.
+func (f sortField[T]) accepts(value any) bool {
+	var zero T
+	switch f.value(zero).(type) {
+	case string:
+		_, ok := value.(string)
+		return ok
+	case int, int32, int64:
+		_, ok := value.(int64)
+		return ok
+	case float32, float64:
+		switch value.(type) {
+		case int64, float64:
+			return true
+		}
+	}
+	return false
+}
+
+// sortFields whitelists what a model can be sorted by, it must contain "id"
+type sortFields[T any] map[string]sortField[T]
+
+// paginate applies ordering, the keyset condition and the limit. One extra
+// row is fetched to find out whether there is a next page.
+func paginate[T any](db *gorm.DB, page Page, fields sortFields[T]) (*gorm.DB, error) {
+	field, ok := fields[page.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSort, page.Sort)
+	}
+
+	dir, op := "asc", ">"
+	if page.Desc {
+		dir, op = "desc", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil || c.Sort != page.Sort || c.Desc != page.Desc || !field.accepts(c.Value) {
+			return nil, ErrInvalidCursor
+		}
+		if _, err := uuid.Parse(c.ID); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		if page.Sort == "id" {
+			db = db.Where("id "+op+" ?", c.ID)
+		} else {
+			db = db.Where(
+				fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", field.column, op, field.column, op),
+				c.Value, c.Value, c.ID,
+			)
+		}
+	}
+
+	if page.Sort != "id" {
+		db = db.Order(field.column + " " + dir)
+	}
+	return db.Order("id " + dir).Limit(page.Limit + 1), nil
+}
+
+// trim drops the extra row fetched by paginate and returns the cursor of the
+// next page, or "" on the last one
+func trim[T any](rows []T, page Page, fields sortFields[T]) ([]T, string) {
+	if len(rows) <= page.Limit {
+		return rows, ""
+	}
+	rows = rows[:page.Limit]
+	last := rows[len(rows)-1]
+	return rows, encodeCursor(page, fields[page.Sort].value(last), fmt.Sprint(fields["id"].value(last)))
+}
+
+func encodeCursor(page Page, value any, id string) string {
+	b, _ := json.Marshal(cursor{Sort: page.Sort, Desc: page.Desc, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor keeps numbers exact, int64 sort keys such as price_amount
+// lose precision above 2^53 as float64
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return c, err
+	}
+	if n, ok := c.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			c.Value = i
+		} else if f, err := n.Float64(); err == nil {
+			c.Value = f
+		} else {
+			return c, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+func TestCursorKeepsLargeIntegers(t *testing.T) {
+	page := Page{Sort: "price", Limit: 1}
+	for _, value := range []any{int64(1<<53 + 1), int64(-1<<62 - 3), 1.5, "name"} {
+		c, err := decodeCursor(encodeCursor(page, value, "id"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Value != value {
+			t.Errorf("cursor value %v (%T), want %v (%T)", c.Value, c.Value, value, value)
+		}
+	}
+}
+
+// TestPaginateByLargePrice pages through amounts float64 cannot tell apart
+func TestPaginateByLargePrice(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "owner@example.com")
+	products := NewProductRepository(db)
+	for _, amount := range []int64{1<<53 + 1, 1<<53 + 2, 1<<53 + 3} {
+		_, err := products.Create(ctx, model.Product{ID: uuid.New(), Name: "Gold", Price: model.NewMoney(amount, "USD"), Quantity: 1, UserID: owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var amounts []int64
+	page := Page{Sort: "price", Limit: 1}
+	for i := 0; i < 5; i++ {
+		rows, next, err := products.GetAll(ctx, ProductFilter{}, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range rows {
+			amounts = append(amounts, p.Price.Amount)
+		}
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	want := []int64{1<<53 + 1, 1<<53 + 2, 1<<53 + 3}
+	if len(amounts) != len(want) {
+		t.Fatalf("paged through %v, want %v", amounts, want)
+	}
+	for i := range want {
+		if amounts[i] != want[i] {
+			t.Fatalf("paged through %v, want %v", amounts, want)
+		}
+	}
+}
+
+func TestPaginateRejectsForgedCursors(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "owner@example.com")
+	products := NewProductRepository(db)
+	if _, err := products.Create(ctx, model.Product{ID: uuid.New(), Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	forge := func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	id := uuid.NewString()
+	tests := []struct {
+		sort   string
+		cursor string
+	}{
+		{"price", forge(`{"s":"price","v":{"a":1},"id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":[1,2],"id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":"100","id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":1.5,"id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":null,"id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":true,"id":"` + id + `"}`)},
+		{"price", forge(`{"s":"price","v":1e400,"id":"` + id + `"}`)},
+		{"quantity", forge(`{"s":"quantity","v":"1","id":"` + id + `"}`)},
+		{"name", forge(`{"s":"name","v":1,"id":"` + id + `"}`)},
+		{"name", forge(`{"s":"name","v":["Chair"],"id":"` + id + `"}`)},
+		{"id", forge(`{"s":"id","v":{},"id":"` + id + `"}`)},
+		{"id", forge(`{"s":"id","v":"x","id":"not a uuid"}`)},
+		{"name", forge(`{"s":"name","v":"Chair","id":{"a":1}}`)},
+		{"name", forge(`{"s":"price","v":"Chair","id":"` + id + `"}`)},
+		{"name", forge(`not json`)},
+		{"name", "%%%"},
+	}
+	for _, tt := range tests {
+		_, _, err := products.GetAll(ctx, ProductFilter{}, Page{Sort: tt.sort, Cursor: tt.cursor})
+		if !errors.Is(err, ErrInvalidCursor) {
+			raw, _ := base64.RawURLEncoding.DecodeString(tt.cursor)
+			t.Errorf("sort %s, cursor %s: %v, want ErrInvalidCursor", tt.sort, raw, err)
+		}
+	}
+
+	// cursors of the right type still page
+	for _, tt := range []struct{ sort, value string }{{"price", "1"}, {"quantity", "0"}, {"name", `"A"`}, {"id", `"` + id + `"`}} {
+		cursor := forge(`{"s":"` + tt.sort + `","v":` + tt.value + `,"id":"` + id + `"}`)
+		if _, _, err := products.GetAll(ctx, ProductFilter{}, Page{Sort: tt.sort, Cursor: cursor}); err != nil {
+			t.Errorf("sort %s, value %s: %v", tt.sort, tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+// ImportOptions control CreateMany. An atomic import stores nothing unless
+// every row can be stored, a dry run stores nothing at all.
+type ImportOptions struct {
+	Atomic bool
+	DryRun bool
+}
+
+// errRollback aborts the import transaction without failing CreateMany
+var errRollback = errors.New("rollback")
+
+// CreateMany inserts products owned by ownerID in one transaction. fn is
+// given create, which stores one product under its own savepoint so a
+// failing product does not hide the errors of the ones after it, and returns
+// the error of that product. Nothing is kept in a dry run, in an atomic
+// import where a product failed, or when fn fails; its error is returned.
+func (r *productRepository) CreateMany(ctx context.Context, ownerID uuid.UUID, opts ImportOptions, fn func(create func(*model.Product) error) error) error {
+	var owner model.User
+	if err := r.db.WithContext(ctx).First(&owner, "id = ?", ownerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user with ID %s not found: %w", ownerID, err)
+		}
+		return err
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		failed := false
+		create := func(product *model.Product) error {
+			product.UserID = ownerID
+			product.Version = 1
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(product).Error; err != nil {
+					return err
+				}
+				return recordChange(tx, productHistoryTable, model.HistoryInsert, product.ID, nil, *product)
+			})
+			failed = failed || err != nil
+			return err
+		}
+		if err := fn(create); err != nil {
+			return err
+		}
+		if opts.DryRun || (opts.Atomic && failed) {
+			return errRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return nil
+	}
+	return err
+}
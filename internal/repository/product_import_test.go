@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+func TestCreateMany(t *testing.T) {
+	ctx := context.Background()
+	failFn := errors.New("source failed")
+	tests := []struct {
+		name   string
+		opts   ImportOptions
+		fnErr  error
+		stored int64
+	}{
+		{"best effort", ImportOptions{}, nil, 2},
+		{"atomic", ImportOptions{Atomic: true}, nil, 0},
+		{"dry run", ImportOptions{DryRun: true}, nil, 0},
+		{"failing source", ImportOptions{}, failFn, 0},
+	}
+	for _, tt := range tests {
+		db := newTestDB(t)
+		owner := createTestUser(t, db, "owner@x.io")
+		products := NewProductRepository(db)
+
+		// the second product reuses the ID of the first and fails
+		first := uuid.New()
+		ids := []uuid.UUID{first, first, uuid.New()}
+		var rowErrs []error
+		err := products.CreateMany(ctx, owner.ID, tt.opts, func(create func(*model.Product) error) error {
+			for _, id := range ids {
+				rowErrs = append(rowErrs, create(&model.Product{ID: id, Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1}))
+			}
+			return tt.fnErr
+		})
+		if !errors.Is(err, tt.fnErr) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.fnErr)
+		}
+		if rowErrs[0] != nil || rowErrs[1] == nil || rowErrs[2] != nil {
+			t.Errorf("%s: row errors %v, want only the second row to fail", tt.name, rowErrs)
+		}
+
+		var stored int64
+		if err := db.Model(&model.Product{}).Where("user_id = ?", owner.ID).Count(&stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored != tt.stored {
+			t.Errorf("%s: %d products stored, want %d", tt.name, stored, tt.stored)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+    "errors"
+    "time"
+
     "github.com/google/uuid"
     "gorm.io/gorm"
     "homework1/internal/models"
@@ -8,13 +12,60 @@ import (
 )
 
 type ProductRepository interface {
-	GetAll() ([]model.Product, error)
-	GetById(id uuid.UUID) (model.Product, error)
-	Create(product model.Product) (model.Product, error)
-	Update(id uuid.UUID,Product model.Product) (model.Product, error)
-	Delete(id uuid.UUID) error
+	GetAll(ctx context.Context, filter ProductFilter, page Page) ([]model.Product, string, error)
+	GetById(ctx context.Context, id uuid.UUID) (model.Product, error)
+	Create(ctx context.Context, product model.Product) (model.Product, error)
+	CreateMany(ctx context.Context, ownerID uuid.UUID, opts ImportOptions, fn func(create func(*model.Product) error) error) error
+	Export(ctx context.Context, filter ProductFilter, fn func([]model.Product) error) error
+	Update(ctx context.Context, id uuid.UUID, version int64, Product model.Product) (model.Product, error)
+	Delete(ctx context.Context, id uuid.UUID, version int64) error
+	GetDeletedById(ctx context.Context, id uuid.UUID) (model.Product, error)
+	Restore(ctx context.Context, id uuid.UUID) (model.Product, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	History(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error)
+	AsOf(ctx context.Context, id uuid.UUID, at time.Time) (model.Product, error)
+	Totals(ctx context.Context) (ProductTotals, error)
+}
+
+var ErrOwnerDeleted = errors.New("the product owner is deleted, restore the user first")
+
+// ErrVersionConflict is returned when a write names a version that is no
+// longer current, a version of 0 skips the check
+var ErrVersionConflict = errors.New("resource has been modified since the given version")
+
+
+// ProductFilter narrows GetAll, nil fields are ignored. Price bounds also
+// restrict the result to their currency.
+type ProductFilter struct {
+    PriceMin   *model.Money
+    PriceMax   *model.Money
+    QuantityLT *int
+    UserID     *uuid.UUID
+    TimeRange
+}
+
+func (f ProductFilter) apply(query *gorm.DB) *gorm.DB {
+    if f.PriceMin != nil {
+        query = query.Where("price_currency = ? AND price_amount >= ?", f.PriceMin.Currency, f.PriceMin.Amount)
+    }
+    if f.PriceMax != nil {
+        query = query.Where("price_currency = ? AND price_amount <= ?", f.PriceMax.Currency, f.PriceMax.Amount)
+    }
+    if f.QuantityLT != nil {
+        query = query.Where("quantity < ?", *f.QuantityLT)
+    }
+    if f.UserID != nil {
+        query = query.Where("user_id = ?", *f.UserID)
+    }
+    return f.TimeRange.apply(query)
 }
 
+var productSortFields = sortFields[model.Product]{
+    "id":       {"id", func(p model.Product) any { return p.ID.String() }},
+    "name":     {"name", func(p model.Product) any { return p.Name }},
+    "price":    {"price_amount", func(p model.Product) any { return p.Price.Amount }},
+    "quantity": {"quantity", func(p model.Product) any { return p.Quantity }},
+}
 
 type productRepository struct {
     db *gorm.DB
@@ -24,36 +75,53 @@ func NewProductRepository(db *gorm.DB) *productRepository {
     return &productRepository{db: db}
 }
 
-func (r *productRepository) GetAll() ([]model.Product, error) {
+func (r *productRepository) GetAll(ctx context.Context, filter ProductFilter, page Page) ([]model.Product, string, error) {
+    page = page.normalize()
+
+    query := filter.apply(r.db.WithContext(ctx).Model(&model.Product{}))
+
+    query, err := paginate(query, page, productSortFields)
+    if err != nil {
+        return nil, "", err
+    }
+
     var products []model.Product
-    if err := r.db.Find(&products).Error; err != nil {
-        return nil, err
+    if err := query.Find(&products).Error; err != nil {
+        return nil, "", err
     }
 
-    return products, nil
+    products, next := trim(products, page, productSortFields)
+    return products, next, nil
 }
 
-func (r *productRepository) GetById(id uuid.UUID) (model.Product, error) {
+func (r *productRepository) GetById(ctx context.Context, id uuid.UUID) (model.Product, error) {
     var p model.Product
-    if err := r.db.First(&p, "id = ?", id).Error; err != nil {
+    if err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error; err != nil {
         return p, err
     }
     return p, nil
 }
 
-func (r *productRepository) Create(p model.Product) (model.Product, error) {
+func (r *productRepository) Create(ctx context.Context, p model.Product) (model.Product, error) {
     var user model.User
     
     // Check if the user with the given UserID exists
-    if err := r.db.First(&user, "id = ?", p.UserID).Error; err != nil {
+    if err := r.db.WithContext(ctx).First(&user, "id = ?", p.UserID).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
-            return p, fmt.Errorf("user with ID %s not found", p.UserID)
+            return p, fmt.Errorf("user with ID %s not found: %w", p.UserID, err)
         }
         return p, err
     }
 
     // If user exists, create the product
-    if err := r.db.Create(&p).Error; err != nil {
+    p.Version = 1
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&p).Error; err != nil {
+            return err
+        }
+        return recordChange(tx, productHistoryTable, model.HistoryInsert, p.ID, nil, p)
+    })
+    if err != nil {
         return p, err
     }
 
@@ -61,26 +129,139 @@ func (r *productRepository) Create(p model.Product) (model.Product, error) {
 }
 
 
-func (r *productRepository) Update(id uuid.UUID, updatedProduct model.Product) (model.Product, error) {
+func (r *productRepository) Update(ctx context.Context, id uuid.UUID, version int64, updatedProduct model.Product) (model.Product, error) {
     var existingProduct model.Product
-    if err := r.db.First(&existingProduct, "id = ?", id).Error; err != nil {
+    if err := r.db.WithContext(ctx).First(&existingProduct, "id = ?", id).Error; err != nil {
         return existingProduct, err
     }
+    if version != 0 && existingProduct.Version != version {
+        return existingProduct, ErrVersionConflict
+    }
+    before := existingProduct
 
     // Update fields
     existingProduct.Name = updatedProduct.Name
     existingProduct.Price = updatedProduct.Price
     existingProduct.Quantity = updatedProduct.Quantity
+    existingProduct.Version++
 
-    if err := r.db.Save(&existingProduct).Error; err != nil {
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := updateVersioned(tx, &existingProduct, before.Version); err != nil {
+            return err
+        }
+        return recordChange(tx, productHistoryTable, model.HistoryUpdate, id, before, existingProduct)
+    })
+    if err != nil {
         return existingProduct, err
     }
     return existingProduct, nil
 }
 
-func (r *productRepository) Delete(id uuid.UUID) error {
-    if err := r.db.Delete(&model.Product{}, "id = ?", id).Error; err != nil {
-        return err
+func (r *productRepository) Delete(ctx context.Context, id uuid.UUID, version int64) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        var p model.Product
+        if err := tx.First(&p, "id = ?", id).Error; err != nil {
+            return err
+        }
+        if version != 0 && p.Version != version {
+            return ErrVersionConflict
+        }
+        return recordEach(tx, productHistoryTable, model.HistoryDelete, []model.Product{p}, productID, func() error {
+            return updateColumnsVersioned(tx, &p, p.Version, map[string]any{"deleted_at": tx.NowFunc()})
+        })
+    })
+}
+
+// GetDeletedById finds a soft deleted product
+func (r *productRepository) GetDeletedById(ctx context.Context, id uuid.UUID) (model.Product, error) {
+    var p model.Product
+    if err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&p, "id = ?", id).Error; err != nil {
+        return p, err
+    }
+    return p, nil
+}
+
+func (r *productRepository) Restore(ctx context.Context, id uuid.UUID) (model.Product, error) {
+    p, err := r.GetDeletedById(ctx, id)
+    if err != nil {
+        return p, err
+    }
+
+    var owner model.User
+    if err := r.db.WithContext(ctx).First(&owner, "id = ?", p.UserID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return p, ErrOwnerDeleted
+        }
+        return p, err
+    }
+
+    err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        return recordEach(tx, productHistoryTable, model.HistoryRestore, []model.Product{p}, productID, func() error {
+            return updateColumnsVersioned(tx.Unscoped(), &p, p.Version, map[string]any{"deleted_at": nil})
+        })
+    })
+    if err != nil {
+        return p, err
+    }
+    return r.GetById(ctx, id)
+}
+
+// Purge permanently removes products soft deleted before the given time
+func (r *productRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+    var purged int64
+    err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        var products []model.Product
+        err := tx.Unscoped().
+            Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore.UTC()).
+            Find(&products).Error
+        if err != nil || len(products) == 0 {
+            return err
+        }
+        return recordEach(tx, productHistoryTable, model.HistoryPurge, products, productID, func() error {
+            ids := make([]uuid.UUID, len(products))
+            for i, p := range products {
+                ids[i] = p.ID
+            }
+            result := tx.Unscoped().Where("id IN ?", ids).Delete(&model.Product{})
+            purged = result.RowsAffected
+            return result.Error
+        })
+    })
+    return purged, err
+}
+
+// History lists every recorded change of the product, oldest first. It
+// fails with gorm.ErrRecordNotFound for a product that never existed.
+func (r *productRepository) History(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error) {
+    entries, err := history(ctx, r.db, productHistoryTable, id)
+    if err != nil || len(entries) > 0 {
+        return entries, err
     }
-    return nil
-}
\ No newline at end of file
+    // rows from before history was recorded have no entries yet
+    var p model.Product
+    if err := r.db.WithContext(ctx).Unscoped().First(&p, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+// AsOf returns the product as it was at the given time
+func (r *productRepository) AsOf(ctx context.Context, id uuid.UUID, at time.Time) (model.Product, error) {
+    var p model.Product
+    err := asOf(ctx, r.db, productHistoryTable, id, at, &p)
+    return p, err
+}
+
+// ProductTotals counts the products that are not deleted and their stock
+type ProductTotals struct {
+    Count    int64
+    Quantity int64
+}
+
+func (r *productRepository) Totals(ctx context.Context) (ProductTotals, error) {
+    var totals ProductTotals
+    err := r.db.WithContext(ctx).Model(&model.Product{}).
+        Select("COUNT(*) AS count, COALESCE(SUM(quantity), 0) AS quantity").
+        Scan(&totals).Error
+    return totals, err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+func createTestProduct(t *testing.T, db *gorm.DB, owner uuid.UUID) model.Product {
+	t.Helper()
+	product, err := NewProductRepository(db).Create(context.Background(), model.Product{
+		ID: uuid.New(), Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// setDeletedAt moves the deletion time of a soft deleted row
+func setDeletedAt(t *testing.T, db *gorm.DB, row any, id uuid.UUID, at time.Time) {
+	t.Helper()
+	if err := db.Unscoped().Model(row).Where("id = ?", id).UpdateColumn("deleted_at", at.UTC()).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	products := NewProductRepository(db)
+	product := createTestProduct(t, db, owner.ID)
+
+	if err := products.Delete(ctx, product.ID, product.Version+1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Delete with a stale version = %v, want ErrVersionConflict", err)
+	}
+	if _, err := products.GetDeletedById(ctx, product.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetDeletedById of a live product = %v, want ErrRecordNotFound", err)
+	}
+	if err := products.Delete(ctx, product.ID, product.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.GetById(ctx, product.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetById of a deleted product = %v, want ErrRecordNotFound", err)
+	}
+	if err := products.Delete(ctx, product.ID, 0); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Delete of a deleted product = %v, want ErrRecordNotFound", err)
+	}
+	deleted, err := products.GetDeletedById(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.DeletedAt.Valid || deleted.Version != product.Version+1 {
+		t.Errorf("deleted product has deleted_at %v and version %d", deleted.DeletedAt, deleted.Version)
+	}
+
+	restored, err := products.Restore(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt.Valid || restored.Version != product.Version+2 {
+		t.Errorf("restored product has deleted_at %v and version %d", restored.DeletedAt, restored.Version)
+	}
+	if _, err := products.Restore(ctx, product.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Restore of a live product = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestRestoreProductOfDeletedOwner(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	products := NewProductRepository(db)
+	product := createTestProduct(t, db, owner.ID)
+
+	if err := products.Delete(ctx, product.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	// the product is already gone, so restrict lets the owner go too
+	if err := NewUserRepository(db).Delete(ctx, owner.ID, 0, RestrictProducts, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Restore(ctx, product.ID); !errors.Is(err, ErrOwnerDeleted) {
+		t.Errorf("Restore = %v, want ErrOwnerDeleted", err)
+	}
+	if _, err := products.GetDeletedById(ctx, product.ID); err != nil {
+		t.Errorf("the product is no longer deleted after a failed restore: %v", err)
+	}
+}
+
+func TestPurgeProducts(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@x.io")
+	products := NewProductRepository(db)
+	now := time.Now().UTC()
+
+	old := createTestProduct(t, db, owner.ID)
+	recent := createTestProduct(t, db, owner.ID)
+	live := createTestProduct(t, db, owner.ID)
+	for _, p := range []model.Product{old, recent} {
+		if err := products.Delete(ctx, p.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setDeletedAt(t, db, &model.Product{}, old.ID, now.Add(-26*time.Hour))
+	setDeletedAt(t, db, &model.Product{}, recent.ID, now.Add(-time.Hour))
+
+	// a cutoff west of UTC reads as earlier if it is compared as written
+	cutoff := now.Add(-24 * time.Hour).In(time.FixedZone("UTC-5", -5*60*60))
+	purged, err := products.Purge(ctx, cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("purged %d products, want 1", purged)
+	}
+	var count int64
+	if err := db.Unscoped().Model(&model.Product{}).Where("id = ?", old.ID).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("the product deleted before the cutoff is still stored (%d, %v)", count, err)
+	}
+	if _, err := products.GetDeletedById(ctx, recent.ID); err != nil {
+		t.Errorf("the product deleted after the cutoff was purged: %v", err)
+	}
+	if _, err := products.GetById(ctx, live.ID); err != nil {
+		t.Errorf("a live product was purged: %v", err)
+	}
+}
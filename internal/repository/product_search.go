@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+// ProductSearcher finds products by words of their name. Every word of the
+// query has to match the start of a word in the name, words being runs of
+// letters and digits.
+type ProductSearcher interface {
+	Search(ctx context.Context, query string, filter ProductFilter, limit, offset int) ([]ProductMatch, error)
+}
+
+// ProductMatch is a search hit. A higher Score ranks better, Snippet is the
+// name as HTML, escaped, with the matching words wrapped in SnippetStart and
+// SnippetEnd.
+type ProductMatch struct {
+	model.Product
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+const (
+	SnippetStart = "<mark>"
+	SnippetEnd   = "</mark>"
+)
+
+// ftsMarkers turns the markers FTS5 puts around matches into SnippetStart
+// and SnippetEnd once the snippet is escaped. They are control characters
+// names do not carry in practice, one that does can only misplace a mark.
+var ftsMarkers = strings.NewReplacer("\x02", SnippetStart, "\x03", SnippetEnd)
+
+var ErrEmptySearch = errors.New("search query has no words")
+
+// NewProductSearcher returns the FTS5 searcher for SQLite and a LIKE based
+// one for the other drivers. SQLite databases migrated by a build without
+// FTS5 have no products_fts index and fall back to LIKE as well. An index
+// made by a build with FTS5 needs FTS5 here too, its triggers fail every
+// product write otherwise.
+func NewProductSearcher(db *gorm.DB, driver string) (ProductSearcher, error) {
+	if driver != "sqlite" {
+		return &likeProductSearcher{db: db}, nil
+	}
+	var indexed bool
+	if err := db.Raw("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'products_fts'").Scan(&indexed).Error; err != nil {
+		return nil, err
+	}
+	if !indexed {
+		slog.Warn("products_fts does not exist, searching products with LIKE")
+		return &likeProductSearcher{db: db}, nil
+	}
+	var enabled bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.New("the products_fts index needs SQLite built with FTS5, build with -tags sqlite_fts5")
+	}
+	return &ftsProductSearcher{db: db}, nil
+}
+
+// searchTerms splits query into lower case words, anything but letters and
+// digits separates them. This also keeps FTS5 query syntax out of the terms.
+func searchTerms(query string) ([]string, error) {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !isWordRune(r)
+	})
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+	return terms, nil
+}
+
+type ftsProductSearcher struct {
+	db *gorm.DB
+}
+
+// Search ranks with bm25, whose best matches are the most negative
+func (s *ftsProductSearcher) Search(ctx context.Context, query string, filter ProductFilter, limit, offset int) ([]ProductMatch, error) {
+	terms, err := searchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = `"` + term + `"*`
+	}
+
+	q := s.db.WithContext(ctx).Table("products_fts").
+		Select("products.*, -bm25(products_fts) AS score, snippet(products_fts, 0, ?, ?, '…', 16) AS snippet", "\x02", "\x03").
+		Joins("JOIN products_fts_docs ON products_fts_docs.doc_id = products_fts.rowid").
+		Joins("JOIN products ON products.id = products_fts_docs.product_id").
+		Where("products_fts MATCH ?", strings.Join(prefixes, " ")).
+		Where("products.deleted_at IS NULL")
+	q = filter.apply(q).Order("score DESC").Order("products.id").Limit(limit).Offset(offset)
+
+	matches := []ProductMatch{}
+	if err := q.Scan(&matches).Error; err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		matches[i].Snippet = ftsMarkers.Replace(html.EscapeString(matches[i].Snippet))
+	}
+	return matches, nil
+}
+
+// likeProductSearcher serves databases without FTS5. It ranks shorter names
+// first, which favours names made mostly of the searched words. LIKE only
+// narrows the rows down, the word boundaries are checked here the way
+// searchTerms draws them, so "cola" finds "Coca-Cola" as it does with FTS5.
+type likeProductSearcher struct {
+	db *gorm.DB
+}
+
+func (s *likeProductSearcher) Search(ctx context.Context, query string, filter ProductFilter, limit, offset int) ([]ProductMatch, error) {
+	terms, err := searchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := s.db.WithContext(ctx).Model(&model.Product{})
+	for _, term := range terms {
+		// terms hold no LIKE wildcards, see searchTerms
+		q = q.Where("LOWER(name) LIKE ?", "%"+term+"%")
+	}
+	rows, err := filter.apply(q).Order("LENGTH(name)").Order("id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []ProductMatch{}
+	for len(matches) < limit && rows.Next() {
+		var p model.Product
+		if err := s.db.ScanRows(rows, &p); err != nil {
+			return nil, err
+		}
+		if !wordsStartWith(p.Name, terms) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		matches = append(matches, ProductMatch{
+			Product: p,
+			Score:   float64(len(terms)) / float64(len(p.Name)+1),
+			Snippet: highlight(p.Name, terms),
+		})
+	}
+	return matches, rows.Err()
+}
+
+// wordsStartWith reports whether every term is a prefix of a word of name,
+// ignoring case
+func wordsStartWith(name string, terms []string) bool {
+	words := strings.FieldsFunc(name, func(r rune) bool { return !isWordRune(r) })
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if prefixLength(word, []string{term}) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight marks the part of every word of name that a term is a prefix of,
+// the name is escaped as HTML
+func highlight(name string, terms []string) string {
+	var b strings.Builder
+	for name != "" {
+		start := strings.IndexFunc(name, isWordRune)
+		if start < 0 {
+			b.WriteString(html.EscapeString(name))
+			break
+		}
+		b.WriteString(html.EscapeString(name[:start]))
+		name = name[start:]
+
+		end := strings.IndexFunc(name, func(r rune) bool { return !isWordRune(r) })
+		if end < 0 {
+			end = len(name)
+		}
+		word := name[:end]
+		if n := prefixLength(word, terms); n > 0 {
+			b.WriteString(SnippetStart + html.EscapeString(word[:n]) + SnippetEnd + html.EscapeString(word[n:]))
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		name = name[end:]
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// prefixLength returns the byte length of the longest term word starts
+// with, ignoring case
+func prefixLength(word string, terms []string) int {
+	best := 0
+	for _, term := range terms {
+		want := utf8.RuneCountInString(term)
+		n, runes := 0, 0
+		for _, r := range word {
+			if runes == want {
+				break
+			}
+			n += utf8.RuneLen(r)
+			runes++
+		}
+		if runes == want && n > best && strings.EqualFold(word[:n], term) {
+			best = n
+		}
+	}
+	return best
+}
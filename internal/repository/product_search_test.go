@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		want  string
+	}{
+		{"Red Chair", []string{"cha"}, "Red <mark>Cha</mark>ir"},
+		{"Red Chair", []string{"re", "chair"}, "<mark>Re</mark>d <mark>Chair</mark>"},
+		{"Red Chair", []string{"blue"}, "Red Chair"},
+		{"<b>Nd</b>", []string{"nd"}, "&lt;b&gt;<mark>Nd</mark>&lt;/b&gt;"},
+		{`Tom & "Jerry"`, []string{"je"}, `Tom &amp; &#34;<mark>Je</mark>rry&#34;`},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.name, tt.terms); got != tt.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.name, tt.terms, got, tt.want)
+		}
+	}
+}
+
+// TestSearchEscapesSnippets runs the FTS5 searcher too when the build has
+// FTS5, see the sqlite_fts5 tag
+func TestSearchEscapesSnippets(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "owner@example.com")
+	_, err := NewProductRepository(db).Create(ctx, model.Product{
+		ID:       uuid.New(),
+		Name:     "Nd <script>x</script> & co",
+		Price:    model.NewMoney(100, "USD"),
+		Quantity: 1,
+		UserID:   owner.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	searchers := map[string]ProductSearcher{"like": &likeProductSearcher{db: db}}
+	searcher, err := NewProductSearcher(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fts, ok := searcher.(*ftsProductSearcher); ok {
+		searchers["fts"] = fts
+	}
+
+	want := "<mark>Nd</mark> &lt;script&gt;x&lt;/script&gt; &amp; co"
+	for name, searcher := range searchers {
+		matches, err := searcher.Search(ctx, "nd", ProductFilter{}, 10, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("%s: got %d matches, want 1", name, len(matches))
+		}
+		if matches[0].Snippet != want {
+			t.Errorf("%s: snippet %q, want %q", name, matches[0].Snippet, want)
+		}
+	}
+}
+
+func TestFTSIndexFollowsProductWrites(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	searcher, err := NewProductSearcher(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := searcher.(*ftsProductSearcher); !ok {
+		t.Skip("SQLite is built without FTS5")
+	}
+	owner := createTestUser(t, db, "owner@example.com")
+	products := NewProductRepository(db)
+	for _, name := range []string{"Red Chair", "Blue Chair"} {
+		_, err := products.Create(ctx, model.Product{ID: uuid.New(), Name: name, Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(query string) int {
+		t.Helper()
+		matches, err := searcher.Search(ctx, query, ProductFilter{}, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(matches)
+	}
+
+	if got := count("chair"); got != 2 {
+		t.Fatalf("chair: got %d matches, want 2", got)
+	}
+	if err := db.Exec("UPDATE products SET name = 'Red Sofa' WHERE name = 'Red Chair'").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := count("chair"); got != 1 {
+		t.Errorf("chair after rename: got %d matches, want 1", got)
+	}
+	if got := count("sofa"); got != 1 {
+		t.Errorf("sofa after rename: got %d matches, want 1", got)
+	}
+	if err := db.Exec("DELETE FROM products WHERE name = 'Blue Chair'").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := count("chair"); got != 0 {
+		t.Errorf("chair after delete: got %d matches, want 0", got)
+	}
+	var docs, indexed int64
+	db.Raw("SELECT COUNT(*) FROM products_fts_docs").Scan(&docs)
+	db.Raw("SELECT COUNT(*) FROM products_fts").Scan(&indexed)
+	if docs != 1 || indexed != 1 {
+		t.Errorf("got %d docs and %d indexed names, want 1 each", docs, indexed)
+	}
+}
+
+// TestSearchWordBoundaries checks that both searchers split names into words
+// alike, the FTS5 one only when the build has FTS5
+func TestSearchWordBoundaries(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "owner@example.com")
+	products := NewProductRepository(db)
+	for _, name := range []string{"Cola", "Granola", "Coca-Cola", "Cola Zero 0.5l", "Diet/cola can"} {
+		_, err := products.Create(ctx, model.Product{ID: uuid.New(), Name: name, Price: model.NewMoney(100, "USD"), Quantity: 1, UserID: owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	searchers := map[string]ProductSearcher{"like": &likeProductSearcher{db: db}}
+	searcher, err := NewProductSearcher(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fts, ok := searcher.(*ftsProductSearcher); ok {
+		searchers["fts"] = fts
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"cola", []string{"Cola", "Coca-Cola", "Cola Zero 0.5l", "Diet/cola can"}},
+		{"ola", nil},
+		{"coca cola", []string{"Coca-Cola"}},
+		{"coca-co", []string{"Coca-Cola"}},
+		{"zero 5", []string{"Cola Zero 0.5l"}},
+		{"gran", []string{"Granola"}},
+	}
+	for name, searcher := range searchers {
+		for _, tt := range tests {
+			matches, err := searcher.Search(ctx, tt.query, ProductFilter{}, 10, 0)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", name, tt.query, err)
+			}
+			got := map[string]bool{}
+			for _, m := range matches {
+				got[m.Name] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s: %s: got %v, want %v", name, tt.query, got, tt.want)
+				continue
+			}
+			for _, want := range tt.want {
+				if !got[want] {
+					t.Errorf("%s: %s: got %v, want %v", name, tt.query, got, tt.want)
+					break
+				}
+			}
+		}
+	}
+
+	// Granola is skipped without using up the offset
+	matches, err := searchers["like"].Search(ctx, "cola", ProductFilter{}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Name != "Coca-Cola" {
+		t.Errorf("like: second match %v, want Coca-Cola", matches)
+	}
+}
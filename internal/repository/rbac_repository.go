@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"homework1/internal/models"
+)
+
+type RBACRepository interface {
+	GetAll(ctx context.Context) ([]model.RolePermission, error)
+	Grant(ctx context.Context, role, permission string) error
+	Revoke(ctx context.Context, role, permission string) error
+	Seed(ctx context.Context, defaults []model.RolePermission) error
+	RecordDenial(ctx context.Context, denial model.AccessDenial) error
+	GetDenials(ctx context.Context, limit int) ([]model.AccessDenial, error)
+}
+
+type rbacRepository struct {
+	db *gorm.DB
+}
+
+func NewRBACRepository(db *gorm.DB) RBACRepository {
+	return &rbacRepository{db: db}
+}
+
+func (r *rbacRepository) GetAll(ctx context.Context) ([]model.RolePermission, error) {
+	var rows []model.RolePermission
+	if err := r.db.WithContext(ctx).Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *rbacRepository) Grant(ctx context.Context, role, permission string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.RolePermission{Role: role, Permission: permission}).Error
+}
+
+func (r *rbacRepository) Revoke(ctx context.Context, role, permission string) error {
+	return r.db.WithContext(ctx).Delete(&model.RolePermission{}, "role = ? AND permission = ?", role, permission).Error
+}
+
+// Seed inserts the defaults only when the table is still empty, so changes
+// made through the API survive a restart
+func (r *rbacRepository) Seed(ctx context.Context, defaults []model.RolePermission) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.RolePermission{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&defaults).Error
+}
+
+func (r *rbacRepository) RecordDenial(ctx context.Context, denial model.AccessDenial) error {
+	return r.db.WithContext(ctx).Create(&denial).Error
+}
+
+func (r *rbacRepository) GetDenials(ctx context.Context, limit int) ([]model.AccessDenial, error) {
+	var denials []model.AccessDenial
+	if err := r.db.WithContext(ctx).Order("created_at desc").Limit(limit).Find(&denials).Error; err != nil {
+		return nil, err
+	}
+	return denials, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error)
+	GetByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+	Revoke(ctx context.Context, id uuid.UUID) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error) {
+	if err := r.db.WithContext(ctx).Create(&token).Error; err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.WithContext(ctx).First(&token, "token_hash = ?", hash).Error; err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// Revoke marks the token revoked and reports whether it was still active,
+// so two concurrent refreshes with the same token cannot both succeed
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"homework1/internal/audit"
+	"homework1/internal/migrations"
+	"homework1/internal/models"
+)
+
+// newTestDB returns a migrated in-memory SQLite database configured like
+// database.InitDB
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		NowFunc:        func() time.Time { return time.Now().UTC() },
+		TranslateError: true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := audit.RegisterCallbacks(db); err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string) model.User {
+	t.Helper()
+	user, err := NewUserRepository(db).Create(context.Background(), model.User{
+		ID:        uuid.New(),
+		FirstName: "Test",
+		LastName:  "User",
+		Email:     email,
+		Password:  "not-a-hash",
+		Role:      model.RoleSeller,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
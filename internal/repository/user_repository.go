@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"homework1/internal/models"
@@ -11,11 +16,57 @@ type userRepository struct {
 }
 
 type UserRepository interface {
-	GetAll() ([]model.User, error)
-	GetById(id uuid.UUID) (model.User, error)
-	Create(user model.User) (model.User, error)
-	Update(id uuid.UUID,user model.User) (model.User, error)
-	Delete(id uuid.UUID) error
+	GetAll(ctx context.Context, filter UserFilter, page Page) ([]model.User, string, error)
+	GetById(ctx context.Context, id uuid.UUID) (model.User, error)
+	GetByEmail(ctx context.Context, email string) (model.User, error)
+	Create(ctx context.Context, user model.User) (model.User, error)
+	Update(ctx context.Context, id uuid.UUID, version int64, user model.User) (model.User, error)
+	Delete(ctx context.Context, id uuid.UUID, version int64, policy ProductPolicy, reassignTo uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) (model.User, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	History(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error)
+	Export(ctx context.Context, filter UserFilter, fn func([]model.User) error) error
+	CountByRole(ctx context.Context) (map[string]int64, error)
+}
+
+// ProductPolicy decides what happens to a user's products when the user is
+// deleted
+type ProductPolicy string
+
+const (
+	// CascadeProducts deletes the products along with the user
+	CascadeProducts ProductPolicy = "cascade"
+	// RestrictProducts refuses to delete a user who still has products
+	RestrictProducts ProductPolicy = "restrict"
+	// ReassignProducts hands the products over to another user
+	ReassignProducts ProductPolicy = "reassign"
+)
+
+var ErrUserHasProducts = errors.New("user still owns products")
+
+// UserFilter narrows GetAll, empty fields are ignored
+type UserFilter struct {
+	Email string
+	Role  string
+	TimeRange
+}
+
+func (f UserFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", f.Email)
+	}
+	if f.Role != "" {
+		query = query.Where("role = ?", f.Role)
+	}
+	return f.TimeRange.apply(query)
+}
+
+var userSortFields = sortFields[model.User]{
+	"id":         {"id", func(u model.User) any { return u.ID.String() }},
+	"first_name": {"first_name", func(u model.User) any { return u.FirstName }},
+	"last_name":  {"last_name", func(u model.User) any { return u.LastName }},
+	"email":      {"email", func(u model.User) any { return u.Email }},
+	"role":       {"role", func(u model.User) any { return u.Role }},
 }
 
 func NewUserRepository(db *gorm.DB) UserRepository {
@@ -24,49 +75,230 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 
 
-func (r *userRepository) GetAll() ([]model.User, error) {
+func (r *userRepository) GetAll(ctx context.Context, filter UserFilter, page Page) ([]model.User, string, error) {
+	page = page.normalize()
+
+	query := filter.apply(r.db.WithContext(ctx).Model(&model.User{}).Preload("Product"))
+
+	query, err := paginate(query, page, userSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var users []model.User
-	if err := r.db.Preload("Product").Find(&users).Error; err != nil {
-		return nil, err
+	if err := query.Find(&users).Error; err != nil {
+		return nil, "", err
 	}
-	return users, nil
+
+	users, next := trim(users, page, userSortFields)
+	return users, next, nil
 }
 
-func (r *userRepository) GetById(id uuid.UUID) (model.User, error) {
+func (r *userRepository) GetById(ctx context.Context, id uuid.UUID) (model.User, error) {
 	var user model.User
-	if err := r.db.Preload("Product").First(&user, "id = ?", id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Product").First(&user, "id = ?", id).Error; err != nil {
 		return user, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) Create(user model.User) (model.User, error) {
-	if err := r.db.Create(&user).Error; err != nil {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error; err != nil {
 		return user, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) Update(id uuid.UUID, updateUser model.User) (model.User, error) {
+func (r *userRepository) Create(ctx context.Context, user model.User) (model.User, error) {
+	user.Version = 1
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, userHistoryTable, model.HistoryInsert, user.ID, nil, user)
+	})
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, id uuid.UUID, version int64, updateUser model.User) (model.User, error) {
 
 	var existingUser model.User
-    if err := r.db.First(&existingUser, "id = ?", id).Error; err != nil {
+    if err := r.db.WithContext(ctx).First(&existingUser, "id = ?", id).Error; err != nil {
         return existingUser, err
     }
+	if version != 0 && existingUser.Version != version {
+		return existingUser, ErrVersionConflict
+	}
 
-	
+	before := existingUser
 	 existingUser.FirstName = updateUser.FirstName
 	 existingUser.LastName = updateUser.LastName
+	if updateUser.Password != "" {
+		existingUser.Password = updateUser.Password
+	}
+	existingUser.Version++
 
-	if err := r.db.Save(&existingUser).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &existingUser, before.Version); err != nil {
+			return err
+		}
+		return recordChange(tx, userHistoryTable, model.HistoryUpdate, id, before, existingUser)
+	})
+	if err != nil {
 		return existingUser, err
 	}
-	return existingUser, nil
+	return r.GetById(ctx, id)
+}
+
+// Delete soft deletes the user and applies the product policy in the same
+// transaction. Cascaded products get the user's deletion time so Restore can
+// bring back exactly those.
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID, version int64, policy ProductPolicy, reassignTo uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if version != 0 && user.Version != version {
+			return ErrVersionConflict
+		}
+
+		products := tx.Model(&model.Product{}).Where("user_id = ?", id)
+		now := tx.NowFunc()
+
+		var owned []model.Product
+		if err := tx.Where("user_id = ?", id).Find(&owned).Error; err != nil {
+			return err
+		}
+
+		switch policy {
+		case CascadeProducts:
+			err := recordEach(tx, productHistoryTable, model.HistoryDelete, owned, productID, func() error {
+				return products.Updates(map[string]any{"deleted_at": now, "version": gorm.Expr("version + 1")}).Error
+			})
+			if err != nil {
+				return err
+			}
+		case RestrictProducts:
+			var count int64
+			if err := products.Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrUserHasProducts
+			}
+		case ReassignProducts:
+			if reassignTo == id {
+				return errors.New("cannot reassign products to the user being deleted")
+			}
+			err := recordEach(tx, productHistoryTable, model.HistoryUpdate, owned, productID, func() error {
+				return products.Updates(map[string]any{"user_id": reassignTo, "version": gorm.Expr("version + 1")}).Error
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown product policy %q", policy)
+		}
+
+		return recordEach(tx, userHistoryTable, model.HistoryDelete, []model.User{user}, userID, func() error {
+			return updateColumnsVersioned(tx, &user, user.Version, map[string]any{"deleted_at": now})
+		})
+	})
 }
 
-func (r *userRepository) Delete(id uuid.UUID) error {
-	if err := r.db.Delete(&model.User{}, "id = ?", id).Error; err != nil {
-		return err
+// Restore undeletes the user and the products that were cascaded with it
+func (r *userRepository) Restore(ctx context.Context, id uuid.UUID) (model.User, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		var cascaded []model.Product
+		err := tx.Unscoped().Where("user_id = ? AND deleted_at = ?", id, user.DeletedAt.Time).Find(&cascaded).Error
+		if err != nil {
+			return err
+		}
+		err = recordEach(tx, productHistoryTable, model.HistoryRestore, cascaded, productID, func() error {
+			return tx.Unscoped().Model(&model.Product{}).
+				Where("user_id = ? AND deleted_at = ?", id, user.DeletedAt.Time).
+				Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEach(tx, userHistoryTable, model.HistoryRestore, []model.User{user}, userID, func() error {
+			return updateColumnsVersioned(tx.Unscoped(), &user, user.Version, map[string]any{"deleted_at": nil})
+		})
+	})
+	if err != nil {
+		return user, err
+	}
+	return r.GetById(ctx, id)
+}
+
+// Purge permanently removes users soft deleted before the given time that
+// no longer own any product row
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []model.User
+		err := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore.UTC()).
+			Where("NOT EXISTS (SELECT 1 FROM products WHERE products.user_id = users.id)").
+			Find(&users).Error
+		if err != nil || len(users) == 0 {
+			return err
+		}
+		return recordEach(tx, userHistoryTable, model.HistoryPurge, users, userID, func() error {
+			ids := make([]uuid.UUID, len(users))
+			for i, u := range users {
+				ids[i] = u.ID
+			}
+			result := tx.Unscoped().Where("id IN ?", ids).Delete(&model.User{})
+			purged = result.RowsAffected
+			return result.Error
+		})
+	})
+	return purged, err
+}
+
+// History lists every recorded change of the user, oldest first
+func (r *userRepository) History(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error) {
+	entries, err := history(ctx, r.db, userHistoryTable, id)
+	if err != nil || len(entries) > 0 {
+		return entries, err
+	}
+	var user model.User
+	if err := r.db.WithContext(ctx).Unscoped().First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CountByRole counts the users that are not deleted, roles without users
+// are left out
+func (r *userRepository) CountByRole(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.User{}).
+		Select("role, COUNT(*) AS count").
+		Group("role").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
 	}
-	return nil
+	return counts, nil
 }
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"homework1/internal/models"
+)
+
+func TestDeleteUserPolicies(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	products := NewProductRepository(db)
+	owner := createTestUser(t, db, "owner@x.io")
+	heir := createTestUser(t, db, "heir@x.io")
+	product := createTestProduct(t, db, owner.ID)
+
+	if err := users.Delete(ctx, owner.ID, 0, RestrictProducts, uuid.Nil); !errors.Is(err, ErrUserHasProducts) {
+		t.Errorf("restrict with products = %v, want ErrUserHasProducts", err)
+	}
+	if err := users.Delete(ctx, owner.ID, 0, ReassignProducts, owner.ID); err == nil {
+		t.Error("reassigning products to the user being deleted succeeded")
+	}
+	if err := users.Delete(ctx, owner.ID, 0, ReassignProducts, heir.ID); err != nil {
+		t.Fatal(err)
+	}
+	reassigned, err := products.GetById(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reassigned.UserID != heir.ID {
+		t.Errorf("product owner is %v, want %v", reassigned.UserID, heir.ID)
+	}
+	if _, err := users.GetById(ctx, owner.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetById of a deleted user = %v, want ErrRecordNotFound", err)
+	}
+}
+
+// TestCascadeRestore checks that restoring a user brings back the products
+// deleted along with it, and only those
+func TestCascadeRestore(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	products := NewProductRepository(db)
+	owner := createTestUser(t, db, "owner@x.io")
+	earlier := createTestProduct(t, db, owner.ID)
+	cascaded := createTestProduct(t, db, owner.ID)
+
+	if err := products.Delete(ctx, earlier.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	setDeletedAt(t, db, &model.Product{}, earlier.ID, time.Now().Add(-time.Hour))
+	if err := users.Delete(ctx, owner.ID, owner.Version, CascadeProducts, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.GetDeletedById(ctx, cascaded.ID); err != nil {
+		t.Fatalf("the product was not deleted with its owner: %v", err)
+	}
+
+	if _, err := users.Restore(ctx, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.GetById(ctx, cascaded.ID); err != nil {
+		t.Errorf("the cascaded product was not restored: %v", err)
+	}
+	if _, err := products.GetDeletedById(ctx, earlier.ID); err != nil {
+		t.Errorf("the product deleted before its owner was restored: %v", err)
+	}
+}
+
+func TestPurgeUsers(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	users := NewUserRepository(db)
+	products := NewProductRepository(db)
+	now := time.Now().UTC()
+
+	alone := createTestUser(t, db, "alone@x.io")
+	owner := createTestUser(t, db, "owner@x.io")
+	recent := createTestUser(t, db, "recent@x.io")
+	createTestProduct(t, db, owner.ID)
+	for _, u := range []model.User{alone, owner, recent} {
+		if err := users.Delete(ctx, u.ID, 0, CascadeProducts, uuid.Nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setDeletedAt(t, db, &model.User{}, alone.ID, now.Add(-26*time.Hour))
+	setDeletedAt(t, db, &model.User{}, owner.ID, now.Add(-26*time.Hour))
+	setDeletedAt(t, db, &model.User{}, recent.ID, now.Add(-time.Hour))
+
+	cutoff := now.Add(-24 * time.Hour).In(time.FixedZone("UTC-5", -5*60*60))
+	purged, err := users.Purge(ctx, cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("purged %d users, want 1", purged)
+	}
+
+	// the owner goes once its products are purged
+	if _, err := products.Purge(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := users.Purge(ctx, cutoff); err != nil || purged != 1 {
+		t.Errorf("purge after the products are gone = %d, %v, want 1", purged, err)
+	}
+	var left []uuid.UUID
+	if err := db.Unscoped().Model(&model.User{}).Pluck("id", &left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0] != recent.ID {
+		t.Errorf("users left %v, want only %v", left, recent.ID)
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import "gorm.io/gorm"
+
+// updateVersioned saves every field of model, whose Version has already been
+// incremented, provided the row is still at the version it was read at
+func updateVersioned(tx *gorm.DB, model any, readVersion int64) error {
+	result := tx.Model(model).Where("version = ?", readVersion).Select("*").Updates(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// updateColumnsVersioned sets the given columns and bumps the version,
+// provided the row is still at the version it was read at
+func updateColumnsVersioned(tx *gorm.DB, model any, readVersion int64, columns map[string]any) error {
+	columns["version"] = gorm.Expr("version + 1")
+	result := tx.Model(model).Where("version = ?", readVersion).Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package routers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+// Define admin handlers
+
+// Purge permanently removes users and products that were soft deleted
+// longer ago than older_than, a Go duration defaulting to the configured
+// retention
+func Purge(userService services.UserService, productService services.ProductService, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		olderThan := retention
+		if value := c.Query("older_than"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", "older_than must be a positive duration such as 720h"))
+				return
+			}
+			olderThan = d
+		}
+		before := time.Now().UTC().Add(-olderThan)
+
+		// products first, a user is only purged once none of its rows remain
+		products, err := productService.PurgeProducts(c.Request.Context(), before)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		users, err := userService.PurgeUsers(c.Request.Context(), before)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"deleted_before": before, "products": products, "users": users})
+	}
+}
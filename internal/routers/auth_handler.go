@@ -0,0 +1,66 @@
+package routers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Define auth handlers
+
+func Login(authService services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req loginRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		tokens, err := authService.Login(c.Request.Context(), req.Email, req.Password)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func Refresh(authService services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req refreshRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		tokens, err := authService.Refresh(c.Request.Context(), req.RefreshToken)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func Logout(authService services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req refreshRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		err := authService.Logout(c.Request.Context(), req.RefreshToken)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,161 @@
+// Renders /openapi.json as a list of operations that can be tried out. Text
+// from the document is only ever set as text, never parsed as HTML.
+"use strict";
+
+const methods = ["get", "post", "put", "patch", "delete"];
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attrs || {})) {
+    node.setAttribute(name, value);
+  }
+  for (const child of children) {
+    node.append(child);
+  }
+  return node;
+}
+
+function json(value) {
+  return el("pre", {}, JSON.stringify(value, null, 2));
+}
+
+function table(head, rows) {
+  return el("table", {},
+    el("tr", {}, ...head.map((h) => el("th", {}, h))),
+    ...rows.map((row) => el("tr", {}, ...row.map((cell) => el("td", {}, cell)))));
+}
+
+// resolve follows a local $ref into the components
+function resolve(doc, schema) {
+  const prefix = "#/components/schemas/";
+  if (schema && schema.$ref && schema.$ref.startsWith(prefix)) {
+    return doc.components.schemas[schema.$ref.slice(prefix.length)] || schema;
+  }
+  return schema;
+}
+
+async function send(path, method, op, inputs, body, output) {
+  let url = path;
+  const query = new URLSearchParams();
+  const headers = {};
+  for (const param of op.parameters || []) {
+    const value = inputs[param.in + ":" + param.name].value;
+    if (value === "") {
+      continue;
+    }
+    if (param.in === "path") {
+      url = url.replace("{" + param.name + "}", encodeURIComponent(value));
+    } else if (param.in === "query") {
+      query.append(param.name, value);
+    } else if (param.in === "header") {
+      headers[param.name] = value;
+    }
+  }
+  if (query.size > 0) {
+    url += "?" + query;
+  }
+  const token = document.getElementById("token").value;
+  if (token !== "") {
+    headers.Authorization = "Bearer " + token;
+  }
+  const init = { method: method.toUpperCase(), headers };
+  if (body) {
+    headers["Content-Type"] = body.type.value;
+    init.body = body.text.value;
+  }
+
+  output.replaceChildren("…");
+  try {
+    const res = await fetch(url, init);
+    const lines = [res.status + " " + res.statusText];
+    res.headers.forEach((value, name) => lines.push(name + ": " + value));
+    let text = await res.text();
+    try {
+      text = JSON.stringify(JSON.parse(text), null, 2);
+    } catch {
+      // not JSON, shown as it is
+    }
+    output.replaceChildren(el("pre", {}, lines.join("\n") + "\n\n" + text));
+  } catch (err) {
+    output.replaceChildren(el("p", { class: "error" }, String(err)));
+  }
+}
+
+function operation(doc, path, method, op) {
+  const content = el("div", {});
+  if (op.description) {
+    content.append(el("p", {}, op.description));
+  }
+
+  const inputs = {};
+  const params = op.parameters || [];
+  if (params.length > 0) {
+    content.append(el("h4", {}, "Parameters"), table(["Name", "In", "Value", "Description"], params.map((param) => {
+      const input = el("input", { placeholder: param.schema && param.schema.enum ? param.schema.enum.join(" | ") : "" });
+      inputs[param.in + ":" + param.name] = input;
+      return [param.name + (param.required ? " *" : ""), param.in, input, param.description || ""];
+    })));
+  }
+
+  let body = null;
+  if (op.requestBody) {
+    const types = Object.keys(op.requestBody.content);
+    const type = el("select", {}, ...types.map((t) => el("option", {}, t)));
+    const text = el("textarea", { spellcheck: "false" });
+    body = { type, text };
+    const schema = el("div", {});
+    const showSchema = () => schema.replaceChildren(json(resolve(doc, op.requestBody.content[type.value].schema)));
+    type.addEventListener("change", showSchema);
+    showSchema();
+    content.append(el("h4", {}, "Body"), type, schema, text);
+  }
+
+  content.append(el("h4", {}, "Responses"), table(["Status", "Description", "Content"],
+    Object.entries(op.responses).map(([status, res]) => [status, res.description || "", Object.keys(res.content || {}).join(", ")])));
+
+  const output = el("div", {});
+  const button = el("button", { type: "button" }, "Send");
+  button.addEventListener("click", () => send(path, method, op, inputs, body, output));
+  content.append(button, output);
+
+  return el("details", {},
+    el("summary", {}, el("span", { class: "method " + method }, method), path, el("span", { class: "summary" }, op.summary || "")),
+    content);
+}
+
+async function render() {
+  const main = document.getElementById("operations");
+  let doc;
+  try {
+    const res = await fetch("/openapi.json");
+    doc = await res.json();
+  } catch (err) {
+    main.replaceChildren(el("p", { class: "error" }, "Could not load the OpenAPI document: " + err));
+    return;
+  }
+  document.getElementById("title").textContent = doc.info.title + " " + doc.info.version;
+  document.getElementById("description").textContent = doc.info.description || "";
+
+  const byTag = new Map();
+  for (const path of Object.keys(doc.paths).sort()) {
+    for (const method of methods) {
+      const op = doc.paths[path][method];
+      if (!op) {
+        continue;
+      }
+      const tag = (op.tags && op.tags[0]) || "other";
+      if (!byTag.has(tag)) {
+        byTag.set(tag, []);
+      }
+      byTag.get(tag).push(operation(doc, path, method, op));
+    }
+  }
+  main.replaceChildren();
+  for (const [tag, ops] of byTag) {
+    main.append(el("h2", {}, tag), ...ops);
+  }
+  main.append(el("h2", {}, "Schemas"), ...Object.entries(doc.components.schemas).map(([name, schema]) =>
+    el("details", {}, el("summary", {}, name), el("div", {}, json(schema)))));
+}
+
+document.addEventListener("DOMContentLoaded", render);
@@ -0,0 +1,80 @@
+package routers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+)
+
+func productETag(p model.Product) string {
+	return fmt.Sprintf(`"%d"`, p.Version)
+}
+
+// userETag covers the embedded products as well, so a GET sees a new tag when
+// one of them changes. Only the leading version is checked by If-Match.
+func userETag(u model.User) string {
+	if len(u.Product) == 0 {
+		return fmt.Sprintf(`"%d"`, u.Version)
+	}
+	h := sha256.New()
+	for _, p := range u.Product {
+		fmt.Fprintf(h, "%s:%d;", p.ID, p.Version)
+	}
+	return fmt.Sprintf(`"%d-%s"`, u.Version, hex.EncodeToString(h.Sum(nil)[:8]))
+}
+
+// ifMatch returns the version named by the If-Match header, 0 for "*". It
+// responds 428 when the header is missing and 412 when it cannot match, in
+// which case ok is false and the handler must return.
+func ifMatch(c *gin.Context) (version int64, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		problem.Write(c, problem.New(http.StatusPreconditionRequired, "precondition_required", "If-Match header with the current ETag is required"))
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+	if strings.Contains(header, ",") {
+		problem.Write(c, problem.New(http.StatusBadRequest, "invalid_if_match", "If-Match must name a single entity tag"))
+		return 0, false
+	}
+
+	// weak tags never match under the strong comparison If-Match uses
+	tag, err := strconv.Unquote(header)
+	if err == nil {
+		tag, _, _ = strings.Cut(tag, "-")
+		version, err = strconv.ParseInt(tag, 10, 64)
+	}
+	if err != nil || version <= 0 {
+		problem.Write(c, problem.New(http.StatusPreconditionFailed, "version_mismatch", "If-Match does not match the current ETag"))
+		return 0, false
+	}
+	return version, true
+}
+
+// notModified answers 304 when If-None-Match names etag, using the weak
+// comparison
+func notModified(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			c.Header("ETag", etag)
+			c.Status(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return true
+		}
+	}
+	return false
+}
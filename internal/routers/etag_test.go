@@ -0,0 +1,175 @@
+package routers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/models"
+	"homework1/internal/services"
+)
+
+// stubProducts keeps one product and checks versions like the repository
+type stubProducts struct {
+	services.ProductService
+	product model.Product
+}
+
+func (s *stubProducts) GetProductById(_ context.Context, id uuid.UUID) (model.Product, error) {
+	if id != s.product.ID {
+		return model.Product{}, services.ErrProductNotFound
+	}
+	return s.product, nil
+}
+
+func (s *stubProducts) UpdateProduct(ctx context.Context, _ services.Actor, id uuid.UUID, version int64, product model.Product) (model.Product, error) {
+	current, err := s.GetProductById(ctx, id)
+	if err != nil {
+		return current, err
+	}
+	if version != 0 && version != current.Version {
+		return current, services.ErrVersionMismatch
+	}
+	product.ID, product.UserID, product.Version = id, current.UserID, current.Version+1
+	s.product = product
+	return product, nil
+}
+
+func (s *stubProducts) DeleteProduct(ctx context.Context, _ services.Actor, id uuid.UUID, version int64) error {
+	current, err := s.GetProductById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != current.Version {
+		return services.ErrVersionMismatch
+	}
+	return nil
+}
+
+func newProductRouter(products services.ProductService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	registerValidators()
+	router := gin.New()
+	router.GET("/products/:id", GetProduct(products))
+	router.PUT("/products/:id", UpdateProduct(products))
+	router.PATCH("/products/:id", PatchProduct(products))
+	router.DELETE("/products/:id", DeleteProduct(products))
+	return router
+}
+
+func TestIfMatch(t *testing.T) {
+	id := uuid.New()
+	const body = `{"name":"Table","price":{"amount":"2.00","currency":"USD"},"quantity":1}`
+	tests := []struct {
+		name    string
+		method  string
+		ifMatch string
+		status  int
+		code    string
+		version int64
+	}{
+		{"current", http.MethodPut, `"3"`, http.StatusOK, "", 4},
+		{"any", http.MethodPut, `*`, http.StatusOK, "", 4},
+		{"user style tag", http.MethodPut, `"3-0123456789abcdef"`, http.StatusOK, "", 4},
+		{"stale", http.MethodPut, `"2"`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"missing", http.MethodPut, "", http.StatusPreconditionRequired, "precondition_required", 3},
+		{"weak", http.MethodPut, `W/"3"`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"unquoted", http.MethodPut, `3`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"zero", http.MethodPut, `"0"`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"list", http.MethodPut, `"3", "4"`, http.StatusBadRequest, "invalid_if_match", 3},
+		{"patch current", http.MethodPatch, `"3"`, http.StatusOK, "", 4},
+		{"patch stale", http.MethodPatch, `"4"`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"patch missing", http.MethodPatch, "", http.StatusPreconditionRequired, "precondition_required", 3},
+		{"delete current", http.MethodDelete, `"3"`, http.StatusNoContent, "", 3},
+		{"delete stale", http.MethodDelete, `"1"`, http.StatusPreconditionFailed, "version_mismatch", 3},
+		{"delete missing", http.MethodDelete, "", http.StatusPreconditionRequired, "precondition_required", 3},
+	}
+	for _, tt := range tests {
+		products := &stubProducts{product: model.Product{ID: id, Name: "Chair", Price: model.NewMoney(100, "USD"), Quantity: 1, Version: 3}}
+		router := newProductRouter(products)
+
+		req := httptest.NewRequest(tt.method, "/products/"+id.String(), strings.NewReader(body))
+		if tt.method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d %s, want %d", tt.name, w.Code, w.Body, tt.status)
+			continue
+		}
+		if tt.code != "" {
+			var problem struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Code != tt.code {
+				t.Errorf("%s: body %s, want code %s", tt.name, w.Body, tt.code)
+			}
+		}
+		if tt.status == http.StatusOK {
+			if etag := w.Header().Get("ETag"); etag != `"4"` {
+				t.Errorf("%s: ETag %q, want the new version", tt.name, etag)
+			}
+		}
+		if products.product.Version != tt.version {
+			t.Errorf("%s: stored version %d, want %d", tt.name, products.product.Version, tt.version)
+		}
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	id := uuid.New()
+	router := newProductRouter(&stubProducts{product: model.Product{ID: id, Name: "Chair", Price: model.NewMoney(100, "USD"), Version: 3}})
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{"", http.StatusOK},
+		{`"3"`, http.StatusNotModified},
+		{`W/"3"`, http.StatusNotModified},
+		{`"1", "3"`, http.StatusNotModified},
+		{`*`, http.StatusNotModified},
+		{`"2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/products/"+id.String(), nil)
+		if tt.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("If-None-Match %s: %d with ETag %q, want %d with \"3\"", tt.ifNoneMatch, w.Code, w.Header().Get("ETag"), tt.status)
+		}
+		if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: 304 with a body %s", tt.ifNoneMatch, w.Body)
+		}
+	}
+}
+
+func TestUserETag(t *testing.T) {
+	user := model.User{Version: 2}
+	if got := userETag(user); got != `"2"` {
+		t.Errorf("userETag without products = %s", got)
+	}
+	user.Product = []model.Product{{ID: uuid.New(), Version: 1}}
+	tag := userETag(user)
+	if !strings.HasPrefix(tag, `"2-`) {
+		t.Errorf("userETag with products = %s, want the user version first", tag)
+	}
+	user.Product[0].Version++
+	if userETag(user) == tag {
+		t.Error("userETag did not change with a product version")
+	}
+}
@@ -0,0 +1,271 @@
+package routers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+	"homework1/internal/xlsx"
+)
+
+// exportFormats are the negotiable export types, CSV is sent when the
+// client accepts anything
+var exportFormats = []string{csvContentType, ndjsonContentType, xlsx.ContentType}
+
+var exportExtensions = map[string]string{
+	csvContentType:    "csv",
+	ndjsonContentType: "ndjson",
+	xlsx.ContentType:  "xlsx",
+}
+
+// exportColumn is one exported field. Dotted names nest in NDJSON, the way
+// the field appears in the JSON API.
+type exportColumn[T any] struct {
+	name  string
+	value func(T) any
+}
+
+var productExportColumns = []exportColumn[model.Product]{
+	{"id", func(p model.Product) any { return p.ID.String() }},
+	{"name", func(p model.Product) any { return p.Name }},
+	{"price.amount", func(p model.Product) any { return xlsx.Number(p.Price.Decimal()) }},
+	{"price.currency", func(p model.Product) any { return string(p.Price.Currency) }},
+	{"quantity", func(p model.Product) any { return p.Quantity }},
+	{"user_id", func(p model.Product) any { return p.UserID.String() }},
+	{"version", func(p model.Product) any { return p.Version }},
+	{"created_at", func(p model.Product) any { return exportTime(p.CreatedAt) }},
+	{"updated_at", func(p model.Product) any { return exportTime(p.UpdatedAt) }},
+}
+
+// userExportColumns deliberately leave out the password
+var userExportColumns = []exportColumn[model.User]{
+	{"id", func(u model.User) any { return u.ID.String() }},
+	{"first_name", func(u model.User) any { return u.FirstName }},
+	{"last_name", func(u model.User) any { return u.LastName }},
+	{"email", func(u model.User) any { return u.Email }},
+	{"role", func(u model.User) any { return u.Role }},
+	{"version", func(u model.User) any { return u.Version }},
+	{"created_at", func(u model.User) any { return exportTime(u.CreatedAt) }},
+	{"updated_at", func(u model.User) any { return exportTime(u.UpdatedAt) }},
+}
+
+func exportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ExportProducts serves GET /products:export with the filters of
+// GET /products
+func ExportProducts(productService services.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseProductFilter(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		writeExport(c, "products", productExportColumns, func(fn func([]model.Product) error) error {
+			return productService.ExportProducts(c.Request.Context(), filter, fn)
+		})
+	}
+}
+
+// ExportUsers serves GET /users:export with the filters of GET /users
+func ExportUsers(userService services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseUserFilter(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		writeExport(c, "users", userExportColumns, func(fn func([]model.User) error) error {
+			return userService.ExportUsers(c.Request.Context(), filter, fn)
+		})
+	}
+}
+
+// writeExport streams the rows export produces in the negotiated format,
+// flushing after every batch
+func writeExport[T any](c *gin.Context, name string, columns []exportColumn[T], export func(fn func([]T) error) error) {
+	format := c.NegotiateFormat(exportFormats...)
+	if format == "" {
+		problem.Write(c, problem.New(http.StatusNotAcceptable, "not_acceptable", "exports are available as "+strings.Join(exportFormats, ", ")))
+		return
+	}
+
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102T150405Z"), exportExtensions[format])
+	c.Header("Content-Type", format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	// a large export streams for longer than the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Status(http.StatusOK)
+
+	values := make([]any, len(columns))
+	w, err := newRowWriter(format, c.Writer, name, names)
+	if err == nil {
+		err = export(func(batch []T) error {
+			for _, row := range batch {
+				for i, column := range columns {
+					values[i] = column.value(row)
+				}
+				if err := w.write(values); err != nil {
+					return err
+				}
+			}
+			if err := w.flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		})
+	}
+	if err == nil {
+		err = w.close()
+	}
+	if err != nil {
+		// once rows have gone out the status cannot change, the client
+		// sees a truncated body
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			problem.Error(c, err)
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "export failed", "export", name, "error", err)
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// rowWriter encodes export rows, nothing reaches the client before flush
+type rowWriter interface {
+	write(values []any) error
+	flush() error
+	close() error
+}
+
+func newRowWriter(format string, w io.Writer, name string, columns []string) (rowWriter, error) {
+	switch format {
+	case ndjsonContentType:
+		return &ndjsonRowWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	case xlsx.ContentType:
+		sheet, err := xlsx.NewWriter(w, strings.ToUpper(name[:1])+name[1:])
+		if err != nil {
+			return nil, err
+		}
+		header := make([]any, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		return &xlsxRowWriter{sheet: sheet}, sheet.WriteRow(header)
+	}
+	cw := csv.NewWriter(w)
+	return &csvRowWriter{csv: cw, record: make([]string, len(columns))}, cw.Write(columns)
+}
+
+// spreadsheetText keeps a user supplied string from being read as a formula
+// when the export is opened in a spreadsheet. Text starting with one of the
+// characters that begin a formula gets a leading quote, the way spreadsheets
+// mark a cell as text.
+func spreadsheetText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type csvRowWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+func (w *csvRowWriter) write(values []any) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+			w.record[i] = spreadsheetText(v)
+		case xlsx.Number:
+			w.record[i] = string(v)
+		case int:
+			w.record[i] = strconv.Itoa(v)
+		case int64:
+			w.record[i] = strconv.FormatInt(v, 10)
+		default:
+			w.record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.csv.Write(w.record)
+}
+
+func (w *csvRowWriter) flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvRowWriter) close() error {
+	return w.flush()
+}
+
+// ndjsonRowWriter writes one object per row. The encoder writes each line
+// straight through, flush has nothing left to do.
+type ndjsonRowWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (w *ndjsonRowWriter) write(values []any) error {
+	object := make(map[string]any, len(values))
+	for i, value := range values {
+		if n, ok := value.(xlsx.Number); ok {
+			value = string(n)
+		}
+		parent := object
+		path := strings.Split(w.columns[i], ".")
+		for _, key := range path[:len(path)-1] {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				parent[key] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = value
+	}
+	return w.enc.Encode(object)
+}
+
+func (w *ndjsonRowWriter) flush() error { return nil }
+
+func (w *ndjsonRowWriter) close() error { return nil }
+
+type xlsxRowWriter struct {
+	sheet *xlsx.Writer
+}
+
+func (w *xlsxRowWriter) write(values []any) error {
+	for i, value := range values {
+		if v, ok := value.(string); ok {
+			values[i] = spreadsheetText(v)
+		}
+	}
+	return w.sheet.WriteRow(values)
+}
+
+func (w *xlsxRowWriter) flush() error {
+	return w.sheet.Flush()
+}
+
+func (w *xlsxRowWriter) close() error {
+	return w.sheet.Close()
+}
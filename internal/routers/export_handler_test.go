@@ -0,0 +1,92 @@
+package routers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"testing"
+
+	"homework1/internal/xlsx"
+)
+
+var formulaCells = map[string]string{
+	"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+	"+1+2":                     "'+1+2",
+	"-2+3":                     "'-2+3",
+	"@SUM(A1)":                 "'@SUM(A1)",
+	"\tcmd":                    "'\tcmd",
+	"\rcmd":                    "'\rcmd",
+	"plain":                    "plain",
+	"a=b":                      "a=b",
+	"":                         "",
+}
+
+func TestSpreadsheetText(t *testing.T) {
+	for in, want := range formulaCells {
+		if got := spreadsheetText(in); got != want {
+			t.Errorf("spreadsheetText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// exportCells writes one row per input through the row writer of format,
+// each row holding the input and a negative number
+func exportCells(t *testing.T, format string, inputs []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newRowWriter(format, &buf, "products", []string{"name", "amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range inputs {
+		if err := w.write([]any{in, xlsx.Number("-1.50")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVExportNeutralizesFormulas(t *testing.T) {
+	var inputs []string
+	for in := range formulaCells {
+		inputs = append(inputs, in)
+	}
+	records, err := csv.NewReader(bytes.NewReader(exportCells(t, csvContentType, inputs))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, in := range inputs {
+		record := records[i+1]
+		if got, want := record[0], formulaCells[in]; got != want {
+			t.Errorf("csv cell for %q = %q, want %q", in, got, want)
+		}
+		if record[1] != "-1.50" {
+			t.Errorf("numbers are left alone, got %q", record[1])
+		}
+	}
+}
+
+func TestXLSXExportNeutralizesFormulas(t *testing.T) {
+	body := exportCells(t, xlsx.ContentType, []string{"=1+1", "-x", "ok"})
+	z, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := z.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{">&#39;=1+1<", ">&#39;-x<", ">ok<", "<v>-1.50</v>"} {
+		if !bytes.Contains(sheet, []byte(want)) {
+			t.Errorf("sheet is missing %s:\n%s", want, sheet)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/health"
+)
+
+// liveness is the body of /healthz
+type liveness struct {
+	Status string `json:"status"`
+}
+
+// Liveness checks no dependency, a database outage should not get the
+// process restarted
+func Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, liveness{Status: health.StatusPass})
+	}
+}
+
+// Readiness answers 503 with the failed checks while the server should not
+// get traffic, the checker logs why they failed
+func Readiness(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Ready(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != health.StatusPass {
+			status = http.StatusServiceUnavailable
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(status, report)
+	}
+}
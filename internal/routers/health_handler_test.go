@@ -0,0 +1,63 @@
+package routers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/health"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var failure error
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(context.Context) error { return failure })
+	router := gin.New()
+	router.GET("/healthz", Liveness())
+	router.GET("/readyz", Readiness(checker))
+
+	get := func(path string) (*httptest.ResponseRecorder, health.Readiness) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Readiness
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		if w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s may be cached", path)
+		}
+		return w, report
+	}
+
+	w, report := get("/readyz")
+	if w.Code != http.StatusOK || report.Status != health.StatusPass || report.Checks["database"].Status != health.StatusPass {
+		t.Errorf("ready: %d %s", w.Code, w.Body)
+	}
+
+	failure = errors.New(`open /var/lib/app/homework1.db: permission denied`)
+	w, report = get("/readyz")
+	if w.Code != http.StatusServiceUnavailable || report.Status != health.StatusFail || report.Checks["database"].Status != health.StatusFail {
+		t.Errorf("failing check: %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "/var/lib") {
+		t.Errorf("readiness shows the cause of the failure: %s", w.Body)
+	}
+	if w, report := get("/healthz"); w.Code != http.StatusOK || report.Status != health.StatusPass {
+		t.Errorf("liveness follows a failing dependency: %d %s", w.Code, w.Body)
+	}
+
+	failure = nil
+	checker.Drain()
+	w, report = get("/readyz")
+	if w.Code != http.StatusServiceUnavailable || report.Checks["shutdown"].Status != health.StatusFail {
+		t.Errorf("draining: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,270 @@
+package routers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"homework1/internal/middleware"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+	"homework1/internal/spool"
+)
+
+// Import body types
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// ImportLimits bound product imports. Imports of more than SyncRows rows run
+// as background jobs, more than MaxRows or MaxBodySize bytes are rejected.
+type ImportLimits struct {
+	SyncRows    int
+	MaxRows     int
+	MaxBodySize int64
+}
+
+// maxImportLineSize bounds an NDJSON line, far more than any product needs
+const maxImportLineSize = 64 << 10
+
+// importSpoolMemory is how much of an import body is held in memory, a
+// larger body waits for its job in a temporary file
+const importSpoolMemory = 1 << 20
+
+// csvColumns are the accepted CSV header names, named like the fields of a
+// product request body
+var csvColumns = []string{"name", "price.amount", "price.currency", "quantity"}
+
+var errTooManyRows = errors.New("too many rows")
+
+// ImportProducts serves POST /products:import. The body is CSV with a header
+// row or NDJSON with one product request body per line. mode is atomic (the
+// default) or best_effort, dry_run=true only validates, and async=true runs
+// the import as a background job regardless of its size.
+func ImportProducts(importService services.ImportService, limits ImportLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := services.ImportOptions{Mode: services.ImportMode(c.DefaultQuery("mode", string(services.ImportAtomic)))}
+		if opts.Mode != services.ImportAtomic && opts.Mode != services.ImportBestEffort {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", "mode must be atomic or best_effort"))
+			return
+		}
+		var err error
+		if opts.DryRun, err = queryBool(c, "dry_run"); err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		async, err := queryBool(c, "async")
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+
+		var parse func(io.Reader, func(services.ImportRow) error) error
+		switch c.ContentType() {
+		case csvContentType:
+			parse = parseCSVRows
+		case ndjsonContentType:
+			parse = parseNDJSONRows
+		default:
+			c.Header("Accept-Post", csvContentType+", "+ndjsonContentType)
+			problem.Write(c, problem.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "import body must be "+csvContentType+" or "+ndjsonContentType))
+			return
+		}
+
+		// the rows of a small import are kept to be stored right away, the
+		// body of a larger one is spooled for its job to read again
+		body := spool.New(importSpoolMemory)
+		var rows []services.ImportRow
+		total := 0
+		err = parse(io.TeeReader(c.Request.Body, body), func(row services.ImportRow) error {
+			if total++; total > limits.MaxRows {
+				return errTooManyRows
+			}
+			if total <= limits.SyncRows {
+				rows = append(rows, row)
+			}
+			return nil
+		})
+		if err != nil {
+			_ = body.Close()
+			switch {
+			case body.Err() != nil:
+				problem.Error(c, err)
+			case errors.Is(err, errTooManyRows):
+				problem.Write(c, problem.New(http.StatusRequestEntityTooLarge, "too_many_rows", fmt.Sprintf("an import may have at most %d rows", limits.MaxRows)))
+			default:
+				problem.Write(c, problem.Body(err))
+			}
+			return
+		}
+
+		source := services.ImportRows(rows)
+		if total > limits.SyncRows {
+			source = func(fn func(services.ImportRow) error) error {
+				defer body.Close()
+				return parse(body.Reader(), fn)
+			}
+		} else {
+			_ = body.Close()
+		}
+
+		if async || total > limits.SyncRows {
+			job := importService.StartProductImport(c.Request.Context(), middleware.Actor(c), source, total, opts)
+			c.Header("Location", "/products/imports/"+job.ID.String())
+			c.JSON(http.StatusAccepted, job)
+			return
+		}
+		report, err := importService.ImportProducts(c.Request.Context(), middleware.Actor(c), source, opts)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func GetImportJob(importService services.ImportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		job, err := importService.GetImportJob(c.Request.Context(), middleware.Actor(c), id)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+func queryBool(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
+
+// parseCSVRows reads a CSV import, passing each row to fn. The header names
+// the columns in any order, a record that cannot be parsed is reported as a
+// row error.
+func parseCSVRows(body io.Reader, fn func(services.ImportRow) error) error {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("CSV body is empty, a header row is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return fmt.Errorf("unknown CSV column %q, columns are %s", name, strings.Join(csvColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("CSV column %q appears twice", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("CSV header is missing the %q column", name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(services.ImportRow{Errors: []services.FieldError{{Code: "malformed", Message: parseErr.Err.Error()}}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(csvRow(record, columns)); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRow builds the product of a record from the columns found in the header
+func csvRow(record []string, columns map[string]int) services.ImportRow {
+	req := productRequest{
+		Name:  record[columns["name"]],
+		Price: &priceRequest{Amount: record[columns["price.amount"]], Currency: record[columns["price.currency"]]},
+	}
+	if value := strings.TrimSpace(record[columns["quantity"]]); value != "" {
+		quantity, err := strconv.Atoi(value)
+		if err != nil {
+			return services.ImportRow{Errors: []services.FieldError{{Field: "quantity", Code: "type", Message: "must be an integer"}}}
+		}
+		req.Quantity = &quantity
+	}
+	return importRow(req)
+}
+
+// parseNDJSONRows reads an NDJSON import, passing each line to fn. Blank
+// lines are skipped, a line longer than maxImportLineSize fails the import.
+func parseNDJSONRows(body io.Reader, fn func(services.ImportRow) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLineSize)
+	for scanner.Scan() {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var row services.ImportRow
+		var req productRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			errs := fieldErrors(err)
+			if malformed(err) {
+				errs = []services.FieldError{{Code: "malformed", Message: "line must be a JSON object"}}
+			}
+			row = services.ImportRow{Errors: errs}
+		} else {
+			row = importRow(req)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("an NDJSON line may be at most %d bytes", maxImportLineSize)
+	}
+	return scanner.Err()
+}
+
+// importRow validates req like the body of POST /products
+func importRow(req productRequest) services.ImportRow {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return services.ImportRow{Errors: fieldErrors(err)}
+	}
+	product, errs := req.toModel()
+	return services.ImportRow{Product: product, Errors: errs}
+}
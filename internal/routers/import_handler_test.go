@@ -0,0 +1,115 @@
+package routers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/middleware"
+	"homework1/internal/services"
+)
+
+// stubImports records the rows handed to it, a job's source is read once
+// the request is over, the way a background job reads it
+type stubImports struct {
+	services.ImportService
+	rows    []services.ImportRow
+	jobRows int
+	source  services.ImportSource
+}
+
+func (s *stubImports) ImportProducts(_ context.Context, _ services.Actor, source services.ImportSource, opts services.ImportOptions) (services.ImportReport, error) {
+	err := source(func(row services.ImportRow) error {
+		s.rows = append(s.rows, row)
+		return nil
+	})
+	return services.ImportReport{ImportOptions: opts, Total: len(s.rows)}, err
+}
+
+func (s *stubImports) StartProductImport(_ context.Context, _ services.Actor, source services.ImportSource, rows int, opts services.ImportOptions) services.ImportJob {
+	s.jobRows, s.source = rows, source
+	return services.ImportJob{ID: uuid.New(), Status: services.JobPending, Rows: rows}
+}
+
+func ndjsonProducts(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `{"name":"product %d","price":{"amount":"1.00","currency":"USD"},"quantity":1}`+"\n", i)
+	}
+	return b.String()
+}
+
+func postImport(t *testing.T, stub *stubImports, limits ImportLimits, body string, contentLength int64) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	registerValidators()
+	router := gin.New()
+	router.POST("/products:import", middleware.LimitBody(limits.MaxBodySize), ImportProducts(stub, limits))
+	req := httptest.NewRequest(http.MethodPost, "/products:import", strings.NewReader(body))
+	req.Header.Set("Content-Type", ndjsonContentType)
+	req.ContentLength = contentLength
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportProductsSizes(t *testing.T) {
+	limits := ImportLimits{SyncRows: 2, MaxRows: 5, MaxBodySize: 4 << 10}
+
+	stub := &stubImports{}
+	if w := postImport(t, stub, limits, ndjsonProducts(2), -1); w.Code != http.StatusOK || len(stub.rows) != 2 {
+		t.Errorf("small import: %d with %d rows, want 200 with 2", w.Code, len(stub.rows))
+	}
+
+	stub = &stubImports{}
+	w := postImport(t, stub, limits, ndjsonProducts(5), -1)
+	if w.Code != http.StatusAccepted || stub.jobRows != 5 || len(stub.rows) != 0 {
+		t.Fatalf("large import: %d with a job of %d rows, want 202 with 5", w.Code, stub.jobRows)
+	}
+	var names []string
+	err := stub.source(func(row services.ImportRow) error {
+		names = append(names, row.Product.Name)
+		return nil
+	})
+	if err != nil || len(names) != 5 || names[0] != "product 1" || names[4] != "product 5" {
+		t.Errorf("job read %v, %v, want the 5 products in order", names, err)
+	}
+
+	stub = &stubImports{}
+	w = postImport(t, stub, limits, ndjsonProducts(6), -1)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "too_many_rows") {
+		t.Errorf("too many rows: %d %s", w.Code, w.Body)
+	}
+
+	// a body past the limit is refused by its length or once it is read
+	big := ndjsonProducts(2) + strings.Repeat(" ", int(limits.MaxBodySize))
+	for _, length := range []int64{-1, int64(len(big))} {
+		stub = &stubImports{}
+		w = postImport(t, stub, limits, big, length)
+		if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "body_too_large") || stub.rows != nil {
+			t.Errorf("length %d: %d %s, want 413 body_too_large", length, w.Code, w.Body)
+		}
+	}
+}
+
+func TestParseNDJSONLineLimit(t *testing.T) {
+	registerValidators()
+	rows := 0
+	count := func(services.ImportRow) error { rows++; return nil }
+
+	if err := parseNDJSONRows(strings.NewReader("\n"+ndjsonProducts(2)+"\n\n"), count); err != nil || rows != 2 {
+		t.Errorf("parsed %d rows, %v, want 2 with blank lines skipped", rows, err)
+	}
+
+	long := `{"name":"` + strings.Repeat("x", maxImportLineSize) + `"}` + "\n"
+	err := parseNDJSONRows(strings.NewReader(ndjsonProducts(1)+long), count)
+	if err == nil || errors.Is(err, errTooManyRows) || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("a line past the limit gave %v", err)
+	}
+}
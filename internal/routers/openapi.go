@@ -0,0 +1,622 @@
+package routers
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"homework1/internal/health"
+	"homework1/internal/middleware"
+	"homework1/internal/models"
+	"homework1/internal/openapi"
+	"homework1/internal/patch"
+	"homework1/internal/problem"
+	"homework1/internal/repository"
+	"homework1/internal/services"
+)
+
+type authMode int
+
+const (
+	authRequired authMode = iota
+	authOptional
+	authNone
+)
+
+// routeDoc describes one route for the OpenAPI document. body and response
+// are Go values whose types give the schemas, or an *openapi.Schema.
+type routeDoc struct {
+	id          string
+	summary     string
+	tag         string
+	auth        authMode
+	permission  string
+	params      []openapi.Parameter
+	body        any
+	bodyTypes   []string
+	status      int
+	response    any
+	resTypes    []string
+	failStatus  int
+	conditional bool
+	ifMatch     bool
+	idempotent  bool
+}
+
+// pageOf and listOf are the envelopes of list responses
+type pageOf struct{ item any }
+type listOf struct{ item any }
+
+// routeDocs documents every route SetupRouter registers, keyed by method and
+// path. Custom methods are keyed by their public path such as
+// /products:import. A route without an entry here is listed as undocumented.
+var routeDocs = map[string]routeDoc{
+	"POST /auth/login": {
+		id: "login", summary: "Log in with email and password", tag: "auth", auth: authNone,
+		body: loginRequest{}, status: http.StatusOK, response: services.TokenPair{},
+	},
+	"POST /auth/refresh": {
+		id: "refreshToken", summary: "Exchange a refresh token for a new token pair", tag: "auth", auth: authNone,
+		body: refreshRequest{}, status: http.StatusOK, response: services.TokenPair{},
+	},
+	"POST /auth/logout": {
+		id: "logout", summary: "Revoke a refresh token", tag: "auth", auth: authNone,
+		body: refreshRequest{}, status: http.StatusNoContent,
+	},
+
+	"GET /users": {
+		id: "listUsers", summary: "List users", tag: "users", permission: model.PermUsersRead,
+		params: concat(pageParams, userFilterParams, timeRangeParams), status: http.StatusOK, response: pageOf{model.User{}},
+	},
+	"GET /users:export": {
+		id: "exportUsers", summary: "Export the users matching the filters", tag: "users", permission: model.PermUsersRead,
+		params: concat(userFilterParams, timeRangeParams), status: http.StatusOK, resTypes: exportFormats,
+	},
+	"GET /users/:id": {
+		id: "getUser", summary: "Get a user", tag: "users", permission: model.PermUsersRead,
+		status: http.StatusOK, response: model.User{}, conditional: true,
+	},
+	"GET /users/:id/history": {
+		id: "getUserHistory", summary: "List the changes of a user", tag: "users", permission: model.PermUsersRead,
+		status: http.StatusOK, response: listOf{model.HistoryEntry{}},
+	},
+	"POST /users": {
+		id: "createUser", summary: "Sign up as a customer, or create a user of any role with users:create", tag: "users", auth: authOptional,
+		body: createUserRequest{}, status: http.StatusCreated, response: model.User{}, idempotent: true,
+	},
+	"PUT /users/:id": {
+		id: "updateUser", summary: "Replace a user", tag: "users", permission: model.PermUsersUpdate,
+		body: updateUserRequest{}, status: http.StatusOK, response: model.User{}, ifMatch: true,
+	},
+	"PATCH /users/:id": {
+		id: "patchUser", summary: "Change some fields of a user", tag: "users", permission: model.PermUsersUpdate,
+		body: updateUserRequest{}, bodyTypes: patchTypes, status: http.StatusOK, response: model.User{}, ifMatch: true,
+	},
+	"DELETE /users/:id": {
+		id: "deleteUser", summary: "Soft delete a user", tag: "users", permission: model.PermUsersDelete,
+		status: http.StatusNoContent, ifMatch: true,
+	},
+	"POST /users/:id/restore": {
+		id: "restoreUser", summary: "Restore a deleted user", tag: "users", permission: model.PermUsersDelete,
+		status: http.StatusOK, response: model.User{},
+	},
+
+	"GET /products": {
+		id: "listProducts", summary: "List products", tag: "products", permission: model.PermProductsRead,
+		params: concat(pageParams, productFilterParams, timeRangeParams), status: http.StatusOK, response: pageOf{model.Product{}},
+	},
+	"GET /products:export": {
+		id: "exportProducts", summary: "Export the products matching the filters", tag: "products", permission: model.PermProductsRead,
+		params: concat(productFilterParams, timeRangeParams), status: http.StatusOK, resTypes: exportFormats,
+	},
+	"POST /products:import": {
+		id: "importProducts", summary: "Import products from CSV or NDJSON", tag: "products", permission: model.PermProductsCreate,
+		params: []openapi.Parameter{
+			query("mode", importMode, "atomic stores all rows or none, best_effort stores the valid ones"),
+			query("dry_run", boolean, "only validate the rows"),
+			query("async", boolean, "run as a background job whatever the size"),
+		},
+		body: productRequest{}, bodyTypes: []string{csvContentType, ndjsonContentType},
+		status: http.StatusOK, response: services.ImportReport{}, idempotent: true,
+	},
+	"GET /products/search": {
+		id: "searchProducts", summary: "Search products by name, best match first", tag: "products", permission: model.PermProductsRead,
+		params: concat([]openapi.Parameter{
+			{Name: "q", In: "query", Required: true, Description: "words to look for, the last one matches as a prefix", Schema: &openapi.Schema{Type: "string"}},
+			query("limit", integer, ""),
+			query("offset", integer, ""),
+		}, productFilterParams, timeRangeParams),
+		status: http.StatusOK, response: listOf{repository.ProductMatch{}},
+	},
+	"GET /products/:id": {
+		id: "getProduct", summary: "Get a product", tag: "products", permission: model.PermProductsRead,
+		params: []openapi.Parameter{query("as_of", dateTime, "return the product as it was at this time")},
+		status: http.StatusOK, response: model.Product{}, conditional: true,
+	},
+	"GET /products/:id/history": {
+		id: "getProductHistory", summary: "List the changes of a product", tag: "products", permission: model.PermProductsRead,
+		status: http.StatusOK, response: listOf{model.HistoryEntry{}},
+	},
+	"POST /products": {
+		id: "createProduct", summary: "Create a product", tag: "products", permission: model.PermProductsCreate,
+		body: productRequest{}, status: http.StatusCreated, response: model.Product{}, idempotent: true,
+	},
+	"GET /products/imports/:id": {
+		id: "getImportJob", summary: "Get a background import job", tag: "products", permission: model.PermProductsCreate,
+		status: http.StatusOK, response: services.ImportJob{},
+	},
+	"PUT /products/:id": {
+		id: "updateProduct", summary: "Replace a product", tag: "products", permission: model.PermProductsUpdate,
+		body: productRequest{}, status: http.StatusOK, response: model.Product{}, ifMatch: true,
+	},
+	"PATCH /products/:id": {
+		id: "patchProduct", summary: "Change some fields of a product", tag: "products", permission: model.PermProductsUpdate,
+		body: productRequest{}, bodyTypes: patchTypes, status: http.StatusOK, response: model.Product{}, ifMatch: true,
+	},
+	"DELETE /products/:id": {
+		id: "deleteProduct", summary: "Soft delete a product", tag: "products", permission: model.PermProductsDelete,
+		status: http.StatusNoContent, ifMatch: true,
+	},
+	"POST /products/:id/restore": {
+		id: "restoreProduct", summary: "Restore a deleted product", tag: "products", permission: model.PermProductsDelete,
+		status: http.StatusOK, response: model.Product{},
+	},
+
+	"GET /rbac/roles": {
+		id: "getRolePermissions", summary: "List the permissions of every role", tag: "rbac", permission: model.PermRBACManage,
+		status: http.StatusOK, response: map[string][]string{},
+	},
+	"GET /rbac/permissions": {
+		id: "getPermissions", summary: "List the known roles and permissions", tag: "rbac", permission: model.PermRBACManage,
+		status: http.StatusOK, response: struct {
+			Roles       []string `json:"roles"`
+			Permissions []string `json:"permissions"`
+		}{},
+	},
+	"PUT /rbac/roles/:role/permissions/:permission": {
+		id: "grantPermission", summary: "Grant a permission to a role", tag: "rbac", permission: model.PermRBACManage,
+		status: http.StatusOK, response: map[string][]string{},
+	},
+	"DELETE /rbac/roles/:role/permissions/:permission": {
+		id: "revokePermission", summary: "Revoke a permission from a role", tag: "rbac", permission: model.PermRBACManage,
+		status: http.StatusOK, response: map[string][]string{},
+	},
+	"GET /rbac/denials": {
+		id: "getAccessDenials", summary: "List recent access denials", tag: "rbac", permission: model.PermRBACManage,
+		params: []openapi.Parameter{query("limit", &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(1000)}, "defaults to 100")},
+		status: http.StatusOK, response: []model.AccessDenial{},
+	},
+
+	"POST /admin/purge": {
+		id: "purge", summary: "Permanently remove long deleted users and products", tag: "admin", permission: model.PermDataPurge,
+		params: []openapi.Parameter{query("older_than", &openapi.Schema{Type: "string", Examples: []any{"720h"}}, "Go duration, defaults to the configured retention")},
+		status: http.StatusOK, response: struct {
+			DeletedBefore time.Time `json:"deleted_before"`
+			Products      int64     `json:"products"`
+			Users         int64     `json:"users"`
+		}{},
+	},
+
+	"GET /openapi.json": {
+		id: "getOpenAPI", summary: "This OpenAPI document", tag: "docs", auth: authNone,
+		status: http.StatusOK, response: &openapi.Schema{Type: "object"},
+	},
+	"GET /docs": {
+		id: "getDocs", summary: "Interactive API documentation", tag: "docs", auth: authNone,
+		status: http.StatusOK, resTypes: []string{"text/html"},
+	},
+	"GET /docs/:file": {
+		id: "getDocsFile", summary: "Script and styles of the documentation page", tag: "docs", auth: authNone,
+		status: http.StatusOK, resTypes: []string{"text/javascript", "text/css"},
+	},
+
+	"GET /healthz": {
+		id: "getLiveness", summary: "Liveness, answers while the process serves requests", tag: "ops", auth: authNone,
+		status: http.StatusOK, response: liveness{},
+	},
+	"GET /readyz": {
+		id: "getReadiness", summary: "Readiness, checks the database, the migrations and the free disk space", tag: "ops", auth: authNone,
+		status: http.StatusOK, response: health.Readiness{}, failStatus: http.StatusServiceUnavailable,
+	},
+	"GET /metrics": {
+		id: "getMetrics", summary: "Metrics in the Prometheus text format", tag: "ops", auth: authNone,
+		status: http.StatusOK, resTypes: []string{"text/plain"},
+	},
+}
+
+var patchTypes = []string{patch.MergePatchType, patch.JSONPatchType}
+
+var (
+	boolean    = &openapi.Schema{Type: "boolean"}
+	integer    = &openapi.Schema{Type: "integer"}
+	dateTime   = &openapi.Schema{Type: "string", Format: "date-time"}
+	uuidType   = &openapi.Schema{Type: "string", Format: "uuid"}
+	amount     = &openapi.Schema{Type: "string", Pattern: `^-?\d+(\.\d+)?$`}
+	importMode = &openapi.Schema{Type: "string", Enum: []any{string(services.ImportAtomic), string(services.ImportBestEffort)}}
+)
+
+var pageParams = []openapi.Parameter{
+	query("limit", &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(repository.MaxPageLimit)}, ""),
+	query("cursor", &openapi.Schema{Type: "string"}, "next_cursor of the previous page"),
+	query("sort", &openapi.Schema{Type: "string"}, "field to sort by, a leading - sorts descending"),
+}
+
+var timeRangeParams = []openapi.Parameter{
+	query("created_after", dateTime, ""),
+	query("created_before", dateTime, ""),
+	query("updated_after", dateTime, ""),
+	query("updated_before", dateTime, ""),
+}
+
+var productFilterParams = []openapi.Parameter{
+	query("currency", openapi.Ref("Currency"), "currency of price_min and price_max, defaults to "+string(model.DefaultCurrency)),
+	query("price_min", amount, ""),
+	query("price_max", amount, ""),
+	query("quantity_lt", integer, ""),
+	query("user_id", uuidType, ""),
+}
+
+var userFilterParams = []openapi.Parameter{
+	query("email", &openapi.Schema{Type: "string"}, ""),
+	query("role", &openapi.Schema{Type: "string", Enum: anySlice(model.Roles)}, ""),
+}
+
+// pathParams holds the schemas of path parameters by name
+var pathParams = map[string]*openapi.Schema{
+	"id":         uuidType,
+	"role":       {Type: "string", Enum: anySlice(model.Roles)},
+	"permission": {Type: "string", Enum: anySlice(model.Permissions)},
+	"file":       {Type: "string", Enum: []any{"docs.js", "docs.css"}},
+}
+
+var (
+	pathParam        = regexp.MustCompile(`/:(\w+)`)
+	customMethodPath = regexp.MustCompile(`^(.*[^/]):(\w+)$`)
+)
+
+// buildSpec generates the document from the registered routes, each
+// described by its routeDocs entry. A route without a usable entry is listed
+// with a bare operation and an entry without a route is left out; problems
+// names both, so the document can be checked against the router.
+func buildSpec(routes gin.RoutesInfo) (doc *openapi.Document, problems []string) {
+	entries := make(map[string][]string, len(routeDocs))
+	for key := range routeDocs {
+		route := key
+		// gin routes a custom method as an :action parameter
+		if m := customMethodPath.FindStringSubmatch(key); m != nil {
+			route = m[1] + ":action"
+		}
+		entries[route] = append(entries[route], key)
+	}
+
+	g := newSchemaGenerator()
+	doc = &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    openapi.Info{Title: "homework1", Version: "1.0.0", Description: "Users and products with role based access control. Errors are RFC 9457 problem details."},
+		Paths:   make(map[string]openapi.PathItem),
+	}
+	add := func(method, path string, op *openapi.Operation) {
+		template := pathParam.ReplaceAllString(path, "/{$1}")
+		if doc.Paths[template] == nil {
+			doc.Paths[template] = make(openapi.PathItem)
+		}
+		doc.Paths[template][strings.ToLower(method)] = op
+	}
+	used := make(map[string]bool, len(routeDocs))
+	for _, route := range routes {
+		keys := entries[route.Method+" "+route.Path]
+		if len(keys) == 0 {
+			problems = append(problems, fmt.Sprintf("%s %s has no OpenAPI entry", route.Method, route.Path))
+			add(route.Method, route.Path, bareOperation(route.Path))
+			continue
+		}
+		for _, key := range keys {
+			used[key] = true
+			_, path, _ := strings.Cut(key, " ")
+			op, err := routeDocs[key].operation(g, path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+				op = bareOperation(path)
+			}
+			add(route.Method, path, op)
+		}
+	}
+	for key := range routeDocs {
+		if !used[key] {
+			problems = append(problems, fmt.Sprintf("OpenAPI entry %s has no route", key))
+		}
+	}
+	sort.Strings(problems)
+
+	doc.Components = openapi.Components{
+		Schemas: g.Schemas(),
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}
+	return doc, problems
+}
+
+// bareOperation stands in for a route buildSpec has no description of
+func bareOperation(path string) *openapi.Operation {
+	op := &openapi.Operation{
+		Summary:   "Undocumented",
+		Responses: map[string]openapi.Response{"default": problemResponse("Problem details")},
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: m[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+	}
+	return op
+}
+
+// newSchemaGenerator knows the types whose JSON differs from their fields
+func newSchemaGenerator() *openapi.Generator {
+	g := openapi.NewGenerator()
+	currency := &openapi.Schema{Type: "string", Description: "ISO 4217 code", Enum: anySlice(model.Currencies())}
+	g.Component("Currency", model.Currency(""), currency)
+	g.Component("Money", model.Money{}, &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"amount":   {Type: "string", Description: "decimal amount in major units", Pattern: amount.Pattern},
+			"currency": openapi.Ref("Currency"),
+		},
+		Required: []string{"amount", "currency"},
+	})
+	g.Schema(problem.Problem{})
+	g.Define(gorm.DeletedAt{}, openapi.Nullable(dateTime))
+	g.Define(services.ImportMode(""), importMode)
+	g.Rule("currency", func(s *openapi.Schema, _ string) { *s = *openapi.Ref("Currency") })
+	g.Rule("role", func(s *openapi.Schema, _ string) { s.Enum = anySlice(model.Roles) })
+	g.Rule("password", func(s *openapi.Schema, _ string) {
+		s.MinLength, s.MaxLength = intPtr(minPasswordLength), intPtr(maxPasswordLength)
+		s.Description = "at least one letter and one digit"
+		s.WriteOnly = true
+	})
+	// passwords are never sent back
+	g.Adjust(model.User{}, func(s *openapi.Schema) {
+		delete(s.Properties, "password")
+		s.Required = remove(s.Required, "password")
+	})
+	healthStatus := func(s *openapi.Schema) {
+		s.Properties["status"].Enum = []any{health.StatusPass, health.StatusFail}
+	}
+	g.Adjust(health.Readiness{}, healthStatus)
+	g.Adjust(health.CheckResult{}, healthStatus)
+	g.Adjust(model.HistoryEntry{}, func(s *openapi.Schema) {
+		s.Properties["snapshot"] = &openapi.Schema{Type: "object", Description: "the entity after the change"}
+		s.Properties["diff"] = &openapi.Schema{Type: "object", Description: "changed fields with their old and new values"}
+		s.Required = append(s.Required, "diff", "snapshot")
+		sort.Strings(s.Required)
+	})
+	return g
+}
+
+func (rd routeDoc) operation(g *openapi.Generator, path string) (*openapi.Operation, error) {
+	op := &openapi.Operation{
+		OperationID: rd.id,
+		Summary:     rd.summary,
+		Tags:        []string{rd.tag},
+		Responses:   make(map[string]openapi.Response),
+	}
+	if rd.permission != "" {
+		op.Description = "Requires the " + rd.permission + " permission."
+	}
+
+	bearer := openapi.SecurityRequirement{"bearer": {}}
+	switch rd.auth {
+	case authRequired:
+		op.Security = []openapi.SecurityRequirement{bearer}
+		op.Responses["401"] = problemResponse("Missing or invalid access token")
+		op.Responses["403"] = problemResponse("Permission denied")
+	case authOptional:
+		op.Security = []openapi.SecurityRequirement{{}, bearer}
+	default:
+		op.Security = []openapi.SecurityRequirement{}
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		schema, ok := pathParams[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("no schema for path parameter %s", m[1])
+		}
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+	}
+	op.Parameters = append(op.Parameters, rd.params...)
+	if rd.conditional {
+		op.Parameters = append(op.Parameters, header("If-None-Match", false, "ETag of a cached copy"))
+		op.Responses["304"] = openapi.Response{Description: "The cached copy is current"}
+	}
+	if rd.ifMatch {
+		op.Parameters = append(op.Parameters, header("If-Match", true, "ETag of the version being changed"))
+		op.Responses["412"] = problemResponse("The resource changed since the ETag was read")
+		op.Responses["428"] = problemResponse("If-Match is missing")
+	}
+	if rd.idempotent {
+		op.Parameters = append(op.Parameters, header(middleware.IdempotencyKeyHeader, false, "retries with the same key replay the first response"))
+	}
+
+	if rd.body != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: make(map[string]openapi.MediaType)}
+		types := rd.bodyTypes
+		if types == nil {
+			types = []string{gin.MIMEJSON}
+		}
+		for _, t := range types {
+			op.RequestBody.Content[t] = openapi.MediaType{Schema: bodySchema(g, t, rd.body)}
+		}
+		op.Responses["422"] = problemResponse("The body is invalid")
+	}
+
+	success := openapi.Response{Description: http.StatusText(rd.status)}
+	if rd.response != nil {
+		success.Content = map[string]openapi.MediaType{gin.MIMEJSON: {Schema: schemaOf(g, rd.response)}}
+	}
+	for _, t := range rd.resTypes {
+		if success.Content == nil {
+			success.Content = make(map[string]openapi.MediaType)
+		}
+		success.Content[t] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+	}
+	if rd.conditional || (rd.ifMatch && rd.status == http.StatusOK) {
+		success.Headers = map[string]openapi.Header{"ETag": {Schema: &openapi.Schema{Type: "string"}}}
+	}
+	op.Responses[strconv.Itoa(rd.status)] = success
+	// the response body is sent with the failure status too
+	if rd.failStatus != 0 {
+		failure := success
+		failure.Description = http.StatusText(rd.failStatus)
+		op.Responses[strconv.Itoa(rd.failStatus)] = failure
+	}
+	op.Responses["default"] = problemResponse("Problem details")
+	return op, nil
+}
+
+// bodySchema describes a request body of the given media type. Merge patches
+// carry any subset of the fields, JSON patches a list of operations and
+// imports one body per CSV row or NDJSON line.
+func bodySchema(g *openapi.Generator, mediaType string, body any) *openapi.Schema {
+	switch mediaType {
+	case patch.MergePatchType:
+		return &openapi.Schema{Type: "object", Description: "any subset of the fields of the body used with PUT"}
+	case patch.JSONPatchType:
+		return &openapi.Schema{Type: "array", Items: g.Schema(patch.Operation{})}
+	case csvContentType:
+		return &openapi.Schema{Type: "string", Description: "header row with the columns " + strings.Join(csvColumns, ", ")}
+	case ndjsonContentType:
+		return &openapi.Schema{Type: "string", Description: "one JSON object per line", ContentSchema: g.Schema(body)}
+	}
+	return schemaOf(g, body)
+}
+
+func schemaOf(g *openapi.Generator, v any) *openapi.Schema {
+	switch v := v.(type) {
+	case *openapi.Schema:
+		return v
+	case pageOf:
+		return &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"data":        {Type: "array", Items: g.Schema(v.item)},
+				"next_cursor": {Type: "string", Description: "absent on the last page"},
+			},
+			Required: []string{"data"},
+		}
+	case listOf:
+		return &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"data": {Type: "array", Items: g.Schema(v.item)}},
+			Required:   []string{"data"},
+		}
+	}
+	return g.Schema(v)
+}
+
+func problemResponse(description string) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{problem.ContentType: {Schema: openapi.Ref("Problem")}},
+	}
+}
+
+func query(name string, schema *openapi.Schema, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func header(name string, required bool, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "header", Required: required, Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func concat(lists ...[]openapi.Parameter) []openapi.Parameter {
+	var all []openapi.Parameter
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+func anySlice[T any](values []T) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func remove(values []string, value string) []string {
+	out := values[:0]
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func float(f float64) *float64 { return &f }
+
+func intPtr(n int) *int { return &n }
+
+// docsFiles is the documentation page with its script and styles, served
+// from the binary so that it runs no third-party code
+//
+//go:embed docs
+var docsFiles embed.FS
+
+// docsTypes are the content types of the files in docs
+var docsTypes = map[string]string{
+	"index.html": "text/html; charset=utf-8",
+	"docs.js":    "text/javascript; charset=utf-8",
+	"docs.css":   "text/css; charset=utf-8",
+}
+
+// docsPolicy keeps the documentation page to its own files and the API
+const docsPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; img-src 'self' data:"
+
+// serveSpec registers the document and its page. The document is built once
+// every other route is registered; what it lacks is logged rather than
+// failing startup, the tests keep it complete.
+func serveSpec(router *gin.Engine) {
+	var document []byte
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, gin.MIMEJSON, document)
+	})
+	router.GET("/docs", func(c *gin.Context) {
+		serveDocsFile(c, "index.html")
+	})
+	router.GET("/docs/:file", func(c *gin.Context) {
+		if c.Param("file") == "index.html" {
+			routeNotFound(c)
+			return
+		}
+		serveDocsFile(c, c.Param("file"))
+	})
+
+	spec, problems := buildSpec(router.Routes())
+	for _, p := range problems {
+		slog.Warn("incomplete OpenAPI document", "problem", p)
+	}
+	var err error
+	if document, err = json.Marshal(spec); err != nil {
+		slog.Error("failed to encode the OpenAPI document", "error", err)
+	}
+}
+
+func serveDocsFile(c *gin.Context, name string) {
+	contentType, ok := docsTypes[name]
+	data, err := docsFiles.ReadFile("docs/" + name)
+	if !ok || err != nil {
+		routeNotFound(c)
+		return
+	}
+	c.Header("Content-Security-Policy", docsPolicy)
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,148 @@
+package routers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/auth"
+	"homework1/internal/health"
+	"homework1/internal/metrics"
+	"homework1/internal/openapi"
+	"homework1/internal/services"
+)
+
+// newTestRouter sets up every route with stub services, none of them is
+// called while the routes are registered
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	signer, err := auth.NewSigner(map[string]string{"test": "test-signing-key-of-enough-length"}, "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := metrics.NewRegistry()
+	router := gin.New()
+	SetupRouter(router,
+		struct{ services.UserService }{},
+		struct{ services.ProductService }{},
+		struct{ services.AuthService }{},
+		struct{ services.RBACService }{},
+		struct{ services.IdempotencyService }{},
+		&stubImports{},
+		ImportLimits{SyncRows: 1, MaxRows: 1, MaxBodySize: 1},
+		signer, time.Hour, registry, metrics.NewHTTP(registry), health.NewChecker(time.Second))
+	return router
+}
+
+// specParam turns an OpenAPI path template back into gin's syntax
+var specParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	router := newTestRouter(t)
+	doc, problems := buildSpec(router.Routes())
+	for _, p := range problems {
+		t.Error(p)
+	}
+
+	// every registered route has an operation, a custom method route one
+	// per method name
+	operations := make(map[string]*openapi.Operation)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			route := specParam.ReplaceAllString(path, ":$1")
+			if m := customMethodPath.FindStringSubmatch(route); m != nil {
+				route = m[1] + ":action"
+			}
+			operations[strings.ToUpper(method)+" "+route+" "+path] = op
+		}
+	}
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+		found := false
+		for key := range operations {
+			found = found || strings.HasPrefix(key, route.Method+" "+route.Path+" ")
+		}
+		if !found {
+			t.Errorf("%s %s is not in the document", route.Method, route.Path)
+		}
+	}
+	ids := make(map[string]string)
+	for key, op := range operations {
+		method, rest, _ := strings.Cut(key, " ")
+		route, path, _ := strings.Cut(rest, " ")
+		if !registered[method+" "+route] {
+			t.Errorf("%s %s is documented but not registered", method, path)
+		}
+		if op.OperationID == "" || op.Summary == "Undocumented" {
+			t.Errorf("%s %s has a bare operation", method, path)
+		}
+		if other, ok := ids[op.OperationID]; ok {
+			t.Errorf("%s %s and %s share the operation ID %s", method, path, other, op.OperationID)
+		}
+		ids[op.OperationID] = method + " " + path
+	}
+}
+
+func TestBuildSpecToleratesUndocumentedRoutes(t *testing.T) {
+	routes := gin.RoutesInfo{
+		{Method: http.MethodGet, Path: "/healthz"},
+		{Method: http.MethodGet, Path: "/widgets/:id"},
+	}
+	doc, problems := buildSpec(routes)
+
+	if op := doc.Paths["/healthz"]["get"]; op == nil || op.OperationID == "" {
+		t.Errorf("documented route missing: %+v", doc.Paths["/healthz"])
+	}
+	op := doc.Paths["/widgets/{id}"]["get"]
+	if op == nil || op.Summary != "Undocumented" || len(op.Parameters) != 1 {
+		t.Errorf("undocumented route got %+v, want a bare operation", op)
+	}
+	if !strings.Contains(strings.Join(problems, "\n"), "GET /widgets/:id has no OpenAPI entry") {
+		t.Errorf("problems %v do not name the undocumented route", problems)
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	router := newTestRouter(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/openapi.json")
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || w.Code != http.StatusOK || len(doc.Paths) == 0 {
+		t.Fatalf("/openapi.json: %d, %v", w.Code, err)
+	}
+
+	for path, contentType := range map[string]string{
+		"/docs":          "text/html",
+		"/docs/docs.js":  "text/javascript",
+		"/docs/docs.css": "text/css",
+	} {
+		w := get(path)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), contentType) {
+			t.Errorf("%s: %d %s", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("Content-Security-Policy") != docsPolicy {
+			t.Errorf("%s has no content security policy", path)
+		}
+		// the page loads nothing from other origins
+		if strings.Contains(w.Body.String(), "https://") {
+			t.Errorf("%s refers to another origin", path)
+		}
+	}
+	for _, path := range []string{"/docs/index.html", "/docs/missing.js"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: %d, want 404", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package routers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+// pageResponse wraps one page of a list endpoint
+type pageResponse struct {
+	Data       any    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parsePage reads limit, cursor and sort from the query string, a leading
+// "-" on sort orders descending
+func parsePage(c *gin.Context) (repository.Page, error) {
+	page := repository.Page{Cursor: c.Query("cursor")}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > repository.MaxPageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", repository.MaxPageLimit)
+		}
+		page.Limit = n
+	}
+
+	sort := c.Query("sort")
+	page.Desc = strings.HasPrefix(sort, "-")
+	page.Sort = strings.TrimPrefix(sort, "-")
+
+	return page, nil
+}
+
+// writePage responds with the page and a Link header pointing at the next
+// one, keeping the caller's filters. The link is relative, the Host header
+// is up to the client.
+func writePage(c *gin.Context, data any, next string) {
+	if next != "" {
+		query := c.Request.URL.Query()
+		query.Set("cursor", next)
+		link := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, link.String()))
+	}
+	c.JSON(http.StatusOK, pageResponse{Data: data, NextCursor: next})
+}
+
+func queryMoney(c *gin.Context, key string, currency model.Currency) (*model.Money, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	m, err := model.ParseMoney(value, currency)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return &m, nil
+}
+
+func queryInt(c *gin.Context, key string) (*int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", key)
+	}
+	return &n, nil
+}
+
+func queryTime(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+	return &t, nil
+}
+
+// parseTimeRange reads created_after, created_before, updated_after and
+// updated_before
+func parseTimeRange(c *gin.Context) (repository.TimeRange, error) {
+	var r repository.TimeRange
+	var err error
+	if r.CreatedAfter, err = queryTime(c, "created_after"); err != nil {
+		return r, err
+	}
+	if r.CreatedBefore, err = queryTime(c, "created_before"); err != nil {
+		return r, err
+	}
+	if r.UpdatedAfter, err = queryTime(c, "updated_after"); err != nil {
+		return r, err
+	}
+	if r.UpdatedBefore, err = queryTime(c, "updated_before"); err != nil {
+		return r, err
+	}
+	return r, nil
+}
@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWritePageLinkIsRelative(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "http://evil.example/products?price_min=1&limit=2", nil)
+
+	writePage(c, []int{1, 2}, "abc")
+
+	want := `</products?cursor=abc&limit=2&price_min=1>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link %q, want %q", got, want)
+	}
+}
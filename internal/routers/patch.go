@@ -0,0 +1,81 @@
+package routers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/patch"
+	"homework1/internal/problem"
+)
+
+// Fields a PATCH may change, anything else in the document is read only
+var (
+	productPatchFields = []string{"name", "price", "quantity"}
+	userPatchFields    = []string{"first_name", "last_name", "password"}
+)
+
+// applyPatch applies the request body to current according to its content
+// type, decodes the result into the request type dest and validates it. It
+// responds with an error and returns false when the patch is malformed,
+// cannot be applied, touches a field outside allowed or leaves the resource
+// invalid.
+func applyPatch(c *gin.Context, current any, allowed []string, dest any) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		problem.Write(c, problem.New(http.StatusBadRequest, "malformed_body", err.Error()))
+		return false
+	}
+	doc, err := patch.Decode(current)
+	if err != nil {
+		problem.Error(c, err)
+		return false
+	}
+
+	var patched any
+	switch c.ContentType() {
+	case patch.MergePatchType:
+		var mergePatch any
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "malformed_body", "invalid merge patch: "+err.Error()))
+			return false
+		}
+		patched = patch.Merge(doc, mergePatch)
+	case patch.JSONPatchType:
+		var ops []patch.Operation
+		if err := json.Unmarshal(body, &ops); err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "malformed_body", "invalid JSON patch: "+err.Error()))
+			return false
+		}
+		patched, err = patch.Apply(doc, ops)
+		if errors.Is(err, patch.ErrTestFailed) {
+			problem.Write(c, problem.New(http.StatusConflict, "patch_test_failed", err.Error()))
+			return false
+		}
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusUnprocessableEntity, "invalid_patch", err.Error()))
+			return false
+		}
+	default:
+		c.Header("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		problem.Write(c, problem.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "PATCH body must be "+patch.MergePatchType+" or "+patch.JSONPatchType))
+		return false
+	}
+
+	for _, field := range patch.ChangedMembers(doc, patched) {
+		if !slices.Contains(allowed, field) {
+			problem.Write(c, problem.New(http.StatusUnprocessableEntity, "read_only_field", fmt.Sprintf("field %q cannot be patched, allowed fields are %s", field, strings.Join(allowed, ", "))))
+			return false
+		}
+	}
+
+	if err := patch.Encode(patched, dest); err != nil {
+		respondInvalid(c, http.StatusUnprocessableEntity, fieldErrors(err))
+		return false
+	}
+	return validate(c, dest)
+}
@@ -1,9 +1,15 @@
 package routers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"homework1/internal/middleware"
+	"homework1/internal/problem"
+	"homework1/internal/repository"
 	"homework1/internal/services"
 	models "homework1/internal/models" // Import the package that defines the Product type
 )
@@ -12,46 +18,169 @@ import (
 
 func GetAllProducts(productService services.ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		products, err := productService.GetAllProducts()
+		filter, err := parseProductFilter(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
 			return
 		}
-		c.JSON(http.StatusOK, products)
+		page, err := parsePage(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		products, next, err := productService.GetAllProducts(c.Request.Context(), filter, page)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		writePage(c, products, next)
 	}
 }
 
+// SearchProducts serves GET /products/search?q=, ranked best first. It takes
+// the filters of GET /products and pages with limit and offset.
+func SearchProducts(productService services.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Query("q")
+		if strings.TrimSpace(query) == "" {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", "q is required"))
+			return
+		}
+		filter, err := parseProductFilter(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		limit, offset, err := parseWindow(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+
+		matches, err := productService.SearchProducts(c.Request.Context(), query, filter, limit, offset)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": matches})
+	}
+}
+
+// parseWindow reads limit and offset for results that are ranked rather
+// than sorted, so cursors do not apply
+func parseWindow(c *gin.Context) (limit, offset int, err error) {
+	limit = repository.DefaultPageLimit
+	n, err := queryInt(c, "limit")
+	if err != nil || (n != nil && (*n <= 0 || *n > repository.MaxPageLimit)) {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", repository.MaxPageLimit)
+	}
+	if n != nil {
+		limit = *n
+	}
+	n, err = queryInt(c, "offset")
+	if err != nil || (n != nil && *n < 0) {
+		return 0, 0, errors.New("offset must be a non-negative integer")
+	}
+	if n != nil {
+		offset = *n
+	}
+	return limit, offset, nil
+}
+
+func parseProductFilter(c *gin.Context) (repository.ProductFilter, error) {
+	var filter repository.ProductFilter
+	var err error
+
+	currency := models.Currency(c.DefaultQuery("currency", string(models.DefaultCurrency)))
+	if filter.PriceMin, err = queryMoney(c, "price_min", currency); err != nil {
+		return filter, err
+	}
+	if filter.PriceMax, err = queryMoney(c, "price_max", currency); err != nil {
+		return filter, err
+	}
+	if filter.QuantityLT, err = queryInt(c, "quantity_lt"); err != nil {
+		return filter, err
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return filter, errors.New("user_id must be a UUID")
+		}
+		filter.UserID = &id
+	}
+	if filter.TimeRange, err = parseTimeRange(c); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
 
 func GetProduct(productService services.ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := uuid.Parse(idStr)
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		asOf, err := queryTime(c, "as_of")
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		if asOf != nil {
+			product, err := productService.GetProductAsOf(c.Request.Context(), id, *asOf)
+			if err != nil {
+				problem.Error(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, product)
 			return
 		}
-		product, err := productService.GetProductById(id)
+		product, err := productService.GetProductById(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			problem.Error(c, err)
 			return
 		}
+		if notModified(c, productETag(product)) {
+			return
+		}
+		c.Header("ETag", productETag(product))
 		c.JSON(http.StatusOK, product)
 	}
 }
 
+// GetProductHistory lists every change of a product, deleted ones included
+func GetProductHistory(productService services.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		entries, err := productService.GetProductHistory(c.Request.Context(), id)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": entries})
+	}
+}
+
 func CreateProduct(productService services.ProductService) gin.HandlerFunc {
     return func(c *gin.Context) {
-        var product models.Product
-        // Bind the JSON input to the model.Product struct
-        if err := c.ShouldBindJSON(&product); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        var req productRequest
+        if !bindJSON(c, &req) {
             return
         }
-        createdProduct, err := productService.CreateProduct(product)
+        product, errs := req.toModel()
+        if errs != nil {
+            respondInvalid(c, http.StatusUnprocessableEntity, errs)
+            return
+        }
+        createdProduct, err := productService.CreateProduct(c.Request.Context(), middleware.Actor(c), product)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            problem.Error(c, err)
             return
         }
+        c.Header("ETag", productETag(createdProduct))
         c.JSON(http.StatusCreated, createdProduct)
     }
 }
@@ -59,40 +188,105 @@ func CreateProduct(productService services.ProductService) gin.HandlerFunc {
 
 func UpdateProduct(productService services.ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := uuid.Parse(idStr)
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		var req productRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		product, errs := req.toModel()
+		if errs != nil {
+			respondInvalid(c, http.StatusUnprocessableEntity, errs)
+			return
+		}
+		updatedProduct, err := productService.UpdateProduct(c.Request.Context(), middleware.Actor(c), id, version, product)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.Header("ETag", productETag(updatedProduct))
+		c.JSON(http.StatusOK, updatedProduct)
+	}
+}
+
+// PatchProduct applies a merge patch or JSON patch to the product, only
+// name, price and quantity may change
+func PatchProduct(productService services.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		current, err := productService.GetProductById(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			problem.Error(c, err)
+			return
+		}
+		if version == 0 {
+			version = current.Version
+		}
+
+		var req productRequest
+		if !applyPatch(c, current, productPatchFields, &req) {
 			return
 		}
-		var product models.Product
-		
-		if err := c.ShouldBindJSON(&product); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		product, errs := req.toModel()
+		if errs != nil {
+			respondInvalid(c, http.StatusUnprocessableEntity, errs)
 			return
 		}
-		updatedProduct, err := productService.UpdateProduct(id, product)
+
+		updatedProduct, err := productService.UpdateProduct(c.Request.Context(), middleware.Actor(c), id, version, product)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Error(c, err)
 			return
 		}
+		c.Header("ETag", productETag(updatedProduct))
 		c.JSON(http.StatusOK, updatedProduct)
 	}
 }
 
 func DeleteProduct(productService services.ProductService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		id, ok := pathID(c, "id")
+		if !ok {
 			return
 		}
-		err = productService.DeleteProduct(id)
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		err := productService.DeleteProduct(c.Request.Context(), middleware.Actor(c), id, version)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Error(c, err)
 			return
 		}
 		c.JSON(http.StatusNoContent, nil)
 	}
 }
+
+func RestoreProduct(productService services.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		product, err := productService.RestoreProduct(c.Request.Context(), middleware.Actor(c), id)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.Header("ETag", productETag(product))
+		c.JSON(http.StatusOK, product)
+	}
+}
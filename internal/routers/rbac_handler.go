@@ -0,0 +1,63 @@
+package routers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+// Define RBAC admin handlers
+
+func GetRolePermissions(rbacService services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, rbacService.GetRolePermissions())
+	}
+}
+
+func GetPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"roles": model.Roles, "permissions": model.Permissions})
+	}
+}
+
+func GrantPermission(rbacService services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := rbacService.Grant(c.Request.Context(), c.Param("role"), c.Param("permission"))
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rbacService.GetRolePermissions())
+	}
+}
+
+func RevokePermission(rbacService services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := rbacService.Revoke(c.Request.Context(), c.Param("role"), c.Param("permission"))
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rbacService.GetRolePermissions())
+	}
+}
+
+func GetAccessDenials(rbacService services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 || limit > 1000 {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", "limit must be between 1 and 1000"))
+			return
+		}
+		denials, err := rbacService.GetDenials(c.Request.Context(), limit)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, denials)
+	}
+}
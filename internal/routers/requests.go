@@ -0,0 +1,65 @@
+package routers
+
+import (
+	"errors"
+
+	"homework1/internal/models"
+	"homework1/internal/services"
+)
+
+// Request bodies. Each is validated by its binding tags and then converted
+// into the model the services work with.
+
+type createUserRequest struct {
+	FirstName string `json:"first_name" binding:"required,max=100"`
+	LastName  string `json:"last_name" binding:"required,max=100"`
+	Email     string `json:"email" binding:"required,email,max=100"`
+	Password  string `json:"password" binding:"required,password"`
+	Role      string `json:"role" binding:"omitempty,role"`
+}
+
+func (r createUserRequest) toModel() model.User {
+	return model.User{
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Email:     r.Email,
+		Password:  r.Password,
+		Role:      r.Role,
+	}
+}
+
+// updateUserRequest leaves the password alone when it is empty
+type updateUserRequest struct {
+	FirstName string `json:"first_name" binding:"required,max=100"`
+	LastName  string `json:"last_name" binding:"required,max=100"`
+	Password  string `json:"password" binding:"omitempty,password"`
+}
+
+func (r updateUserRequest) toModel() model.User {
+	return model.User{FirstName: r.FirstName, LastName: r.LastName, Password: r.Password}
+}
+
+type priceRequest struct {
+	Amount   string `json:"amount" binding:"required"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+type productRequest struct {
+	Name     string        `json:"name" binding:"required,max=100"`
+	Price    *priceRequest `json:"price" binding:"required"`
+	Quantity *int          `json:"quantity" binding:"required,gte=0"`
+}
+
+// toModel parses the price, which the binding tags can only check loosely
+func (r productRequest) toModel() (model.Product, []services.FieldError) {
+	price, err := model.ParseMoney(r.Price.Amount, model.Currency(r.Price.Currency))
+	switch {
+	case errors.Is(err, model.ErrTooPrecise):
+		return model.Product{}, []services.FieldError{{Field: "price.amount", Code: "precision", Message: err.Error()}}
+	case err != nil:
+		return model.Product{}, []services.FieldError{{Field: "price.amount", Code: "decimal", Message: "must be a decimal string such as \"12.34\""}}
+	case price.Amount <= 0:
+		return model.Product{}, []services.FieldError{{Field: "price.amount", Code: "gt", Message: "must be greater than 0"}}
+	}
+	return model.Product{Name: r.Name, Price: price, Quantity: *r.Quantity}, nil
+}
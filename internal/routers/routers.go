@@ -2,28 +2,120 @@ package routers
 
 
 import (
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"homework1/internal/auth"
+	"homework1/internal/health"
+	"homework1/internal/metrics"
+	"homework1/internal/middleware"
+	"homework1/internal/models"
+	"homework1/internal/problem"
 	"homework1/internal/services"
 )
 
-func SetupRouter(router *gin.Engine, userService services.UserService, productService services.ProductService) {
-	// User routes
+func SetupRouter(router *gin.Engine, userService services.UserService, productService services.ProductService, authService services.AuthService, rbacService services.RBACService, idempotencyService services.IdempotencyService, importService services.ImportService, importLimits ImportLimits, signer *auth.Signer, purgeRetention time.Duration, registry *metrics.Registry, httpMetrics *metrics.HTTP, checker *health.Checker) {
+	registerValidators()
+
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(routeNotFound)
+	router.NoMethod(func(c *gin.Context) {
+		problem.Write(c, problem.New(http.StatusMethodNotAllowed, "method_not_allowed", c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+	})
+
+	authenticate := middleware.Authenticate(signer)
+	require := func(permission string) gin.HandlerFunc {
+		return middleware.RequirePermission(rbacService, permission)
+	}
+	idempotent := middleware.Idempotent(idempotencyService)
+
+	// Auth routes
+	authGroup := router.Group("/auth")
+	{
+		authGroup.POST("/login", Login(authService))
+		authGroup.POST("/refresh", Refresh(authService))
+		authGroup.POST("/logout", Logout(authService))
+	}
+
+	// User routes, sign up stays public
 	userGroup := router.Group("/users")
 	{
-		userGroup.GET("", GetAllUsers(userService))
-		userGroup.GET("/:id", GetUser(userService))
-		userGroup.POST("", CreateUser(userService))
-		userGroup.PUT("/:id", UpdateUser(userService))
-		userGroup.DELETE("/:id", DeleteUser(userService))
+		userGroup.GET("", authenticate, require(model.PermUsersRead), GetAllUsers(userService))
+		userGroup.GET("/:id", authenticate, require(model.PermUsersRead), GetUser(userService))
+		userGroup.GET("/:id/history", authenticate, require(model.PermUsersRead), GetUserHistory(userService))
+		userGroup.POST("", middleware.OptionalAuthenticate(signer), idempotent, CreateUser(userService, rbacService))
+		userGroup.PUT("/:id", authenticate, require(model.PermUsersUpdate), UpdateUser(userService))
+		userGroup.PATCH("/:id", authenticate, require(model.PermUsersUpdate), PatchUser(userService))
+		userGroup.DELETE("/:id", authenticate, require(model.PermUsersDelete), DeleteUser(userService))
+		userGroup.POST("/:id/restore", authenticate, require(model.PermUsersDelete), RestoreUser(userService))
 	}
 
 	 // Product routes
-	productGroup := router.Group("/products")
+	productGroup := router.Group("/products", authenticate)
+	{
+		productGroup.GET("", require(model.PermProductsRead), GetAllProducts(productService))
+		productGroup.GET("/search", require(model.PermProductsRead), SearchProducts(productService))
+		productGroup.GET("/:id", require(model.PermProductsRead), GetProduct(productService))
+		productGroup.GET("/:id/history", require(model.PermProductsRead), GetProductHistory(productService))
+		productGroup.POST("", require(model.PermProductsCreate), idempotent, CreateProduct(productService))
+		productGroup.GET("/imports/:id", require(model.PermProductsCreate), GetImportJob(importService))
+		productGroup.PUT("/:id", require(model.PermProductsUpdate), UpdateProduct(productService))
+		productGroup.PATCH("/:id", require(model.PermProductsUpdate), PatchProduct(productService))
+		productGroup.DELETE("/:id", require(model.PermProductsDelete), DeleteProduct(productService))
+		productGroup.POST("/:id/restore", require(model.PermProductsDelete), RestoreProduct(productService))
+	}
+
+	// Custom methods are registered on the router since a group would put a
+	// slash before the colon
+	router.GET("/users:action", customMethod("export"), authenticate, require(model.PermUsersRead), ExportUsers(userService))
+	router.POST("/products:action", customMethod("import"), authenticate, require(model.PermProductsCreate), middleware.LimitBody(importLimits.MaxBodySize), idempotent, ImportProducts(importService, importLimits))
+	router.GET("/products:action", customMethod("export"), authenticate, require(model.PermProductsRead), ExportProducts(productService))
+
+	// Role-permission management
+	rbacGroup := router.Group("/rbac", authenticate, require(model.PermRBACManage))
 	{
-		productGroup.GET("", GetAllProducts(productService))
-		productGroup.GET("/:id", GetProduct(productService))
-		productGroup.POST("", CreateProduct(productService))
-		productGroup.PUT("/:id", UpdateProduct(productService))
-		productGroup.DELETE("/:id", DeleteProduct(productService))
+		rbacGroup.GET("/roles", GetRolePermissions(rbacService))
+		rbacGroup.GET("/permissions", GetPermissions())
+		rbacGroup.PUT("/roles/:role/permissions/:permission", GrantPermission(rbacService))
+		rbacGroup.DELETE("/roles/:role/permissions/:permission", RevokePermission(rbacService))
+		rbacGroup.GET("/denials", GetAccessDenials(rbacService))
+	}
+
+	// Maintenance
+	adminGroup := router.Group("/admin", authenticate)
+	{
+		adminGroup.POST("/purge", require(model.PermDataPurge), Purge(userService, productService, purgeRetention))
+	}
+
+	// Probes and metrics are public so that the orchestrator and Prometheus
+	// can reach them
+	router.GET("/healthz", Liveness())
+	router.GET("/readyz", Readiness(checker))
+	router.GET("/metrics", gin.WrapH(registry.Handler()))
+
+	// API documentation, built last so that it sees every route
+	serveSpec(router)
+	// routeDocs is keyed by the labels the metrics middleware uses, every
+	// route has a latency series before it is first requested
+	for key := range routeDocs {
+		method, route, _ := strings.Cut(key, " ")
+		httpMetrics.Init(method, route)
+	}
+}
+
+func routeNotFound(c *gin.Context) {
+	problem.Write(c, problem.New(http.StatusNotFound, "route_not_found", "no route for "+c.Request.Method+" "+c.Request.URL.Path))
+}
+
+// customMethod guards a custom method route such as POST /products:import.
+// gin cannot route a literal colon, so the route is registered with an
+// :action parameter, anything but ":"+name is not found.
+func customMethod(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param("action") != ":"+name {
+			routeNotFound(c)
+		}
 	}
 }
@@ -0,0 +1,146 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"homework1/internal/auth"
+	"homework1/internal/health"
+	"homework1/internal/metrics"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+	"homework1/internal/services"
+)
+
+// stubRBAC answers from the default role table
+type stubRBAC struct{ services.RBACService }
+
+func (stubRBAC) HasPermission(role, permission string) bool {
+	for _, p := range services.DefaultRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (stubRBAC) RecordDenial(context.Context, model.AccessDenial) error { return nil }
+
+// stubUsers creates every user it is given and lists none
+type stubUsers struct{ services.UserService }
+
+func (stubUsers) CreateUser(_ context.Context, user model.User) (model.User, error) {
+	user.ID = uuid.New()
+	return user, nil
+}
+
+func (stubUsers) GetAllUsers(context.Context, repository.UserFilter, repository.Page) ([]model.User, string, error) {
+	return nil, "", nil
+}
+
+// newAccessRouter sets up every route with the default role table and
+// returns a function issuing access tokens for a role
+func newAccessRouter(t *testing.T) (*gin.Engine, func(role string) string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	signer, err := auth.NewSigner(map[string]string{"test": "test-signing-key-of-enough-length"}, "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := metrics.NewRegistry()
+	router := gin.New()
+	SetupRouter(router,
+		stubUsers{},
+		struct{ services.ProductService }{},
+		struct{ services.AuthService }{},
+		stubRBAC{},
+		struct{ services.IdempotencyService }{},
+		&stubImports{},
+		ImportLimits{SyncRows: 1, MaxRows: 1, MaxBodySize: 1},
+		signer, time.Hour, registry, metrics.NewHTTP(registry), health.NewChecker(time.Second))
+	return router, func(role string) string {
+		token, _, err := signer.Sign(uuid.New(), role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+}
+
+func TestProtectedRoutes(t *testing.T) {
+	router, tokenFor := newAccessRouter(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		status int
+		code   string
+	}{
+		{"no token", http.MethodGet, "/users", "", http.StatusUnauthorized, "missing_token"},
+		{"not bearer", http.MethodGet, "/users", "Basic " + tokenFor(model.RoleAdmin), http.StatusUnauthorized, "missing_token"},
+		{"bad token", http.MethodGet, "/users", "Bearer not.a.token", http.StatusUnauthorized, "invalid_token"},
+		{"no token on a group", http.MethodGet, "/rbac/roles", "", http.StatusUnauthorized, "missing_token"},
+		{"no token on a custom method", http.MethodGet, "/users:export", "", http.StatusUnauthorized, "missing_token"},
+		{"customer lists users", http.MethodGet, "/users", "Bearer " + tokenFor(model.RoleCustomer), http.StatusForbidden, "missing_permission"},
+		{"seller lists users", http.MethodGet, "/users", "Bearer " + tokenFor(model.RoleSeller), http.StatusForbidden, "missing_permission"},
+		{"seller manages roles", http.MethodGet, "/rbac/roles", "Bearer " + tokenFor(model.RoleSeller), http.StatusForbidden, "missing_permission"},
+		{"customer creates a product", http.MethodPost, "/products", "Bearer " + tokenFor(model.RoleCustomer), http.StatusForbidden, "missing_permission"},
+		{"seller purges", http.MethodPost, "/admin/purge", "Bearer " + tokenFor(model.RoleSeller), http.StatusForbidden, "missing_permission"},
+		{"admin lists users", http.MethodGet, "/users", "Bearer " + tokenFor(model.RoleAdmin), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.code) {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, w.Code, w.Body, tt.status, tt.code)
+		}
+		if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", tt.name)
+		}
+	}
+}
+
+// TestSignUpRoles checks that only callers allowed to create users pick a
+// role other than customer
+func TestSignUpRoles(t *testing.T) {
+	router, tokenFor := newAccessRouter(t)
+	tests := []struct {
+		caller string
+		role   string
+		status int
+	}{
+		{"", "", http.StatusCreated},
+		{"", model.RoleCustomer, http.StatusCreated},
+		{"", model.RoleSeller, http.StatusForbidden},
+		{"", model.RoleAdmin, http.StatusForbidden},
+		{model.RoleCustomer, model.RoleSeller, http.StatusForbidden},
+		{model.RoleSeller, model.RoleSeller, http.StatusForbidden},
+		{model.RoleSeller, model.RoleAdmin, http.StatusForbidden},
+		{model.RoleAdmin, model.RoleSeller, http.StatusCreated},
+		{model.RoleAdmin, model.RoleAdmin, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		body := `{"first_name":"A","last_name":"B","email":"a@example.com","password":"passw0rd1","role":"` + tt.role + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.caller != "" {
+			req.Header.Set("Authorization", "Bearer "+tokenFor(tt.caller))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%q signing up as %q: %d %s, want %d", tt.caller, tt.role, w.Code, w.Body, tt.status)
+		}
+	}
+}
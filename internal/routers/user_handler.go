@@ -4,7 +4,9 @@ package routers
 import(
 	"net/http"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"homework1/internal/middleware"
+	"homework1/internal/problem"
+	"homework1/internal/repository"
 	"homework1/internal/services"	
 	models "homework1/internal/models"
 )
@@ -13,69 +15,183 @@ import(
 // Define route handlers
 func GetAllUsers(userService services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		users, err := (userService).GetAllUsers();
+		page, err := parsePage(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
 			return
 		}
-		c.JSON(http.StatusOK, users)
+		filter, err := parseUserFilter(c)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, "invalid_query", err.Error()))
+			return
+		}
+		users, next, err := (userService).GetAllUsers(c.Request.Context(), filter, page);
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		writePage(c, users, next)
 	}
 }
 
+func parseUserFilter(c *gin.Context) (repository.UserFilter, error) {
+	filter := repository.UserFilter{Email: c.Query("email"), Role: c.Query("role")}
+	var err error
+	filter.TimeRange, err = parseTimeRange(c)
+	return filter, err
+}
+
 func GetUser(userService services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := uuid.Parse(c.Param("id"))
-		user, err := (userService).GetUserById(id)
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		user, err := (userService).GetUserById(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			problem.Error(c, err)
+			return
+		}
+		if notModified(c, userETag(user)) {
 			return
 		}
+		c.Header("ETag", userETag(user))
 		c.JSON(http.StatusOK, user)
 	}
 }
 
-func CreateUser(userService services.UserService) gin.HandlerFunc {
+func GetUserHistory(userService services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.User
-		if err := c.ShouldBindJSON(&user); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		id, ok := pathID(c, "id")
+		if !ok {
 			return
 		}
-		createdUser, err := (userService).CreateUser(user)
+		entries, err := userService.GetUserHistory(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Error(c, err)
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"data": entries})
+	}
+}
+
+// CreateUser doubles as public sign up, only callers allowed to create users
+// may hand out the admin role
+func CreateUser(userService services.UserService, rbacService services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createUserRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		user := req.toModel()
+		// anyone may sign up as a customer, any other role is assigned by a
+		// caller allowed to create users
+		if user.Role != "" && user.Role != models.RoleCustomer && !rbacService.HasPermission(middleware.Role(c), models.PermUsersCreate) {
+			problem.Write(c, problem.New(http.StatusForbidden, "forbidden_role", "Not allowed to assign role "+user.Role))
+			return
+		}
+		createdUser, err := (userService).CreateUser(c.Request.Context(), user)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.Header("ETag", userETag(createdUser))
 		c.JSON(http.StatusCreated, createdUser)
 	}
 }
 
 func UpdateUser(userService services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := uuid.Parse(c.Param("id"))
-		var user models.User
-		if err := c.ShouldBindJSON(&user); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		id, ok := pathID(c, "id")
+		if !ok {
 			return
 		}
-		updatedUser, err := (userService).UpdateUser(id, user)
-		
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		var req updateUserRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		updatedUser, err := (userService).UpdateUser(c.Request.Context(), id, version, req.toModel())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Error(c, err)
 			return
 		}
+		c.Header("ETag", userETag(updatedUser))
+		c.JSON(http.StatusOK, updatedUser)
+	}
+}
+
+// PatchUser applies a merge patch or JSON patch to the user, only the names
+// and the password may change
+func PatchUser(userService services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		current, err := (userService).GetUserById(c.Request.Context(), id)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		if version == 0 {
+			version = current.Version
+		}
+
+		var req updateUserRequest
+		if !applyPatch(c, current, userPatchFields, &req) {
+			return
+		}
+		user := req.toModel()
+
+		updatedUser, err := (userService).UpdateUser(c.Request.Context(), id, version, user)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.Header("ETag", userETag(updatedUser))
 		c.JSON(http.StatusOK, updatedUser)
 	}
 }
 
 func DeleteUser(userService services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := uuid.Parse(c.Param("id"))
-		err := (userService).DeleteUser(id)
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		version, ok := ifMatch(c)
+		if !ok {
+			return
+		}
+		err := (userService).DeleteUser(c.Request.Context(), middleware.Actor(c), id, version)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			problem.Error(c, err)
 			return
 		}
 		c.JSON(http.StatusNoContent, nil)
 	}
 }
+
+func RestoreUser(userService services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c, "id")
+		if !ok {
+			return
+		}
+		user, err := (userService).RestoreUser(c.Request.Context(), id)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.Header("ETag", userETag(user))
+		c.JSON(http.StatusOK, user)
+	}
+}
@@ -0,0 +1,189 @@
+package routers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"homework1/internal/models"
+	"homework1/internal/problem"
+	"homework1/internal/services"
+)
+
+// Password policy for new passwords
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+var registerOnce sync.Once
+
+// registerValidators names fields by their JSON key and adds the custom
+// rules used by the request types
+func registerValidators() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		v.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+			return model.IsRole(fl.Field().String())
+		})
+		v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+			return model.Currency(fl.Field().String()).Valid()
+		})
+		v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+			return validPassword(fl.Field().String())
+		})
+	})
+}
+
+// validPassword requires a length within the policy and at least one letter
+// and one digit
+func validPassword(password string) bool {
+	if n := len([]rune(password)); n < minPasswordLength || n > maxPasswordLength {
+		return false
+	}
+	var letter, digit bool
+	for _, r := range password {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	return letter && digit
+}
+
+// bindJSON decodes and validates the body into req, responding with the
+// field errors when it fails
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		status := http.StatusUnprocessableEntity
+		if malformed(err) {
+			status = http.StatusBadRequest
+		}
+		respondInvalid(c, status, fieldErrors(err))
+		return false
+	}
+	return true
+}
+
+// validate runs the binding rules of req without decoding anything
+func validate(c *gin.Context, req any) bool {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		respondInvalid(c, http.StatusUnprocessableEntity, fieldErrors(err))
+		return false
+	}
+	return true
+}
+
+// pathID parses a UUID path parameter
+func pathID(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, []services.FieldError{{
+			Field:   name,
+			Code:    "uuid",
+			Message: name + " must be a UUID",
+		}})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondInvalid(c *gin.Context, status int, errs []services.FieldError) {
+	problem.Write(c, problem.Invalid(status, errs))
+}
+
+// fieldErrors turns binding errors into FieldErrors
+func fieldErrors(err error) []services.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		errs := make([]services.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			errs = append(errs, fieldError(fe))
+		}
+		return errs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []services.FieldError{{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: fmt.Sprintf("must be a %s", jsonType(typeErr.Type)),
+		}}
+	}
+
+	if malformed(err) {
+		return []services.FieldError{{Code: "malformed", Message: "request body must be a JSON object"}}
+	}
+	return []services.FieldError{{Code: "invalid", Message: err.Error()}}
+}
+
+// malformed reports a body that is not JSON at all, as opposed to JSON
+// with invalid fields
+func malformed(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func fieldError(fe validator.FieldError) services.FieldError {
+	// the namespace starts with the struct name, e.g. productRequest.price.amount
+	_, field, _ := strings.Cut(fe.Namespace(), ".")
+	e := services.FieldError{Field: field, Code: fe.Tag()}
+
+	switch fe.Tag() {
+	case "required":
+		e.Message = "is required"
+	case "email":
+		e.Message = "must be a valid email address"
+	case "max":
+		e.Message = "must be at most " + fe.Param() + " characters"
+	case "gte":
+		e.Message = "must be at least " + fe.Param()
+	case "gt":
+		e.Message = "must be greater than " + fe.Param()
+	case "role":
+		e.Message = "must be one of " + strings.Join(model.Roles, ", ")
+	case "currency":
+		e.Message = "must be a supported ISO 4217 currency code"
+	case "password":
+		e.Message = fmt.Sprintf("must be %d to %d characters with at least one letter and one digit", minPasswordLength, maxPasswordLength)
+	default:
+		e.Message = "failed the " + fe.Tag() + " rule"
+	}
+	return e
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
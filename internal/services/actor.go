@@ -0,0 +1,16 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"homework1/internal/models"
+)
+
+// Actor is the authenticated caller a service call is made on behalf of
+type Actor struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == model.RoleAdmin
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"homework1/internal/auth"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type AuthService interface {
+	Login(ctx context.Context, email, plain string) (TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+var ErrInvalidRefreshToken = Unauthorized("invalid_refresh_token", "invalid refresh token")
+
+type authService struct {
+	users      UserService
+	tokens     repository.RefreshTokenRepository
+	signer     *auth.Signer
+	refreshTTL time.Duration
+}
+
+func NewAuthService(users UserService, tokens repository.RefreshTokenRepository, signer *auth.Signer, refreshTTL time.Duration) AuthService {
+	return &authService{users: users, tokens: tokens, signer: signer, refreshTTL: refreshTTL}
+}
+
+func (s *authService) Login(ctx context.Context, email, plain string) (TokenPair, error) {
+	user, err := s.users.Authenticate(ctx, email, plain)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issue(ctx, user)
+}
+
+// Refresh rotates the refresh token. Presenting a token that was already
+// rotated or revoked revokes every token of that user, since it means the
+// token has leaked.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	stored, err := s.tokens.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil {
+		s.tokens.RevokeAllForUser(ctx, stored.UserID)
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	revoked, err := s.tokens.Revoke(ctx, stored.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !revoked {
+		s.tokens.RevokeAllForUser(ctx, stored.UserID)
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.users.GetUserById(ctx, stored.UserID)
+	if err != nil {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	return s.issue(ctx, user)
+}
+
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := s.tokens.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	_, err = s.tokens.Revoke(ctx, stored.ID)
+	return err
+}
+
+func (s *authService) issue(ctx context.Context, user model.User) (TokenPair, error) {
+	access, expiresAt, err := s.signer.Sign(user.ID, user.Role)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return TokenPair{}, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err = s.tokens.Create(ctx, model.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(expiresAt).Round(time.Second).Seconds()),
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
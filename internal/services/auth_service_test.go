@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"homework1/internal/auth"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+func newTestAuth(t *testing.T) (AuthService, *auth.Signer, model.User) {
+	t.Helper()
+	db := newTestDB(t)
+	users := NewUserService(repository.NewUserRepository(db), repository.RestrictProducts)
+	user, err := users.CreateUser(context.Background(), model.User{
+		ID: uuid.New(), FirstName: "Ann", LastName: "Lee", Email: "ann@example.com", Password: "secret-pw",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := auth.NewSigner(map[string]string{"test": "test-signing-key-of-enough-length"}, "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthService(users, repository.NewRefreshTokenRepository(db), signer, time.Hour), signer, user
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	auths, signer, user := newTestAuth(t)
+
+	if _, err := auths.Login(ctx, user.Email, "wrong-pw"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := auths.Login(ctx, "nobody@example.com", "secret-pw"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown email: %v, want ErrInvalidCredentials", err)
+	}
+
+	pair, err := auths.Login(ctx, user.Email, "secret-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := signer.Parse(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != user.ID || claims.Role != model.RoleCustomer {
+		t.Errorf("claims %+v, want the customer %s", claims, user.ID)
+	}
+	if pair.RefreshToken == "" || pair.TokenType != "Bearer" || pair.ExpiresIn != 60 {
+		t.Errorf("pair %+v", pair)
+	}
+}
+
+func TestRefreshRotates(t *testing.T) {
+	ctx := context.Background()
+	auths, signer, user := newTestAuth(t)
+	first, err := auths.Login(ctx, user.Email, "secret-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := auths.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("refresh returned the same refresh token")
+	}
+	if claims, err := signer.Parse(second.AccessToken); err != nil || claims.Subject != user.ID {
+		t.Errorf("rotated access token: %+v, %v", claims, err)
+	}
+
+	third, err := auths.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("refreshing with the rotated token: %v", err)
+	}
+
+	if _, err := auths.Refresh(ctx, "not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if err := auths.Logout(ctx, third.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auths.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after logout: %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+// TestRefreshReuseRevokesAll presents a refresh token a second time, as a
+// thief holding a copy would, and expects every token of the user revoked
+func TestRefreshReuseRevokesAll(t *testing.T) {
+	ctx := context.Background()
+	auths, _, user := newTestAuth(t)
+	stolen, err := auths.Login(ctx, user.Email, "secret-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDevice, err := auths.Login(ctx, user.Email, "secret-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := auths.Refresh(ctx, stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := auths.Refresh(ctx, stolen.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reusing a rotated token: %v, want ErrInvalidRefreshToken", err)
+	}
+	for name, token := range map[string]string{"rotated": rotated.RefreshToken, "other device": otherDevice.RefreshToken} {
+		if _, err := auths.Refresh(ctx, token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%s token after reuse: %v, want ErrInvalidRefreshToken", name, err)
+		}
+	}
+
+	// logging in again starts a new family
+	fresh, err := auths.Login(ctx, user.Email, "secret-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auths.Refresh(ctx, fresh.RefreshToken); err != nil {
+		t.Errorf("refresh after a new login: %v", err)
+	}
+}
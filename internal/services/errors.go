@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"homework1/internal/repository"
+)
+
+// ErrorKind classifies a domain error, the HTTP layer maps each kind to a
+// status code
+type ErrorKind string
+
+const (
+	KindNotFound     ErrorKind = "not_found"
+	KindConflict     ErrorKind = "conflict"
+	KindValidation   ErrorKind = "validation"
+	KindForbidden    ErrorKind = "forbidden"
+	KindUnauthorized ErrorKind = "unauthorized"
+	KindPrecondition ErrorKind = "precondition_failed"
+	KindBadRequest   ErrorKind = "bad_request"
+)
+
+// FieldError describes one invalid field of a request. Field is the JSON
+// path of the field, e.g. "price.amount", or the name of a parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a stable, machine readable Code. Two errors
+// match with errors.Is when their codes are equal, so a wrapped copy still
+// matches the variable it was made from.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// wrap returns a copy of e with err as its cause
+func (e *Error) wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// BadRequest is for malformed input outside the request body, such as a
+// query parameter
+func BadRequest(code, message string) *Error {
+	return &Error{Kind: KindBadRequest, Code: code, Message: message}
+}
+
+func PreconditionFailed(code, message string) *Error {
+	return &Error{Kind: KindPrecondition, Code: code, Message: message}
+}
+
+var (
+	ErrUserNotFound    = NotFound("user_not_found", "user not found")
+	ErrProductNotFound = NotFound("product_not_found", "product not found")
+	ErrVersionMismatch = PreconditionFailed("version_mismatch", "the resource has been modified since the given version")
+	ErrEmailTaken      = Conflict("email_taken", "a user with this email already exists")
+	ErrOwnerDeleted    = Conflict("owner_deleted", "the product owner is deleted, restore the user first")
+	ErrUserHasProducts = Conflict("user_has_products", "the user still owns products")
+	ErrNoVersionAsOf   = NotFound("no_version_at_time", "the resource did not exist at that time")
+	ErrInvalidCursor   = BadRequest("invalid_cursor", "invalid cursor")
+	ErrInvalidSort     = BadRequest("invalid_sort", "invalid sort field")
+	ErrInvalidSearch   = BadRequest("invalid_search", "the search query must contain a letter or digit")
+)
+
+// translate turns repository errors into domain errors, notFound is used for
+// a missing row. Unknown errors are returned as they are.
+func translate(err error, notFound *Error) error {
+	var domain *Error
+	switch {
+	case err == nil, errors.As(err, &domain):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return notFound.wrap(err)
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionMismatch.wrap(err)
+	case errors.Is(err, repository.ErrOwnerDeleted):
+		return ErrOwnerDeleted.wrap(err)
+	case errors.Is(err, repository.ErrUserHasProducts):
+		return ErrUserHasProducts.wrap(err)
+	case errors.Is(err, repository.ErrNoHistory):
+		return ErrNoVersionAsOf.wrap(err)
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return ErrInvalidCursor.wrap(err)
+	case errors.Is(err, repository.ErrEmptySearch):
+		return ErrInvalidSearch.wrap(err)
+	case errors.Is(err, repository.ErrInvalidSort):
+		e := ErrInvalidSort.wrap(err)
+		e.Message = err.Error()
+		return e
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+	"homework1/internal/repository"
+)
+
+func TestTranslate(t *testing.T) {
+	plain := errors.New("disk full")
+	tests := []struct {
+		err  error
+		want error
+		kind ErrorKind
+	}{
+		{gorm.ErrRecordNotFound, ErrProductNotFound, KindNotFound},
+		{fmt.Errorf("user with ID x not found: %w", gorm.ErrRecordNotFound), ErrProductNotFound, KindNotFound},
+		{repository.ErrVersionConflict, ErrVersionMismatch, KindPrecondition},
+		{repository.ErrOwnerDeleted, ErrOwnerDeleted, KindConflict},
+		{repository.ErrUserHasProducts, ErrUserHasProducts, KindConflict},
+		{repository.ErrNoHistory, ErrNoVersionAsOf, KindNotFound},
+		{repository.ErrInvalidCursor, ErrInvalidCursor, KindBadRequest},
+		{repository.ErrEmptySearch, ErrInvalidSearch, KindBadRequest},
+		{fmt.Errorf("%w: price", repository.ErrInvalidSort), ErrInvalidSort, KindBadRequest},
+		{ErrEmailTaken, ErrEmailTaken, KindConflict},
+	}
+	for _, tt := range tests {
+		err := translate(tt.err, ErrProductNotFound)
+		var domain *Error
+		if !errors.Is(err, tt.want) || !errors.As(err, &domain) || domain.Kind != tt.kind {
+			t.Errorf("translate(%v) = %v, want %v of kind %s", tt.err, err, tt.want, tt.kind)
+			continue
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("translate(%v) lost its cause", tt.err)
+		}
+	}
+
+	if err := translate(plain, ErrProductNotFound); err != plain {
+		t.Errorf("translate(%v) = %v, want it unchanged", plain, err)
+	}
+	if err := translate(nil, ErrProductNotFound); err != nil {
+		t.Errorf("translate(nil) = %v", err)
+	}
+	sortErr := fmt.Errorf("%w: price", repository.ErrInvalidSort)
+	if err := translate(sortErr, ErrProductNotFound); err.Error() != sortErr.Error() {
+		t.Errorf("invalid sort message %q, want %q naming the field", err, sortErr)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+// IdempotencyService remembers the responses to requests sent with an
+// Idempotency-Key so retries do not repeat their side effects
+type IdempotencyService interface {
+	Begin(ctx context.Context, scope, key, fingerprint string) (model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, record model.IdempotencyKey) error
+	Abandon(ctx context.Context, id uuid.UUID) error
+}
+
+var (
+	ErrIdempotencyKeyReused     = Validation("idempotency_key_reused", "the Idempotency-Key was already used with a different request body")
+	ErrIdempotencyKeyInProgress = Conflict("idempotency_key_in_progress", "a request with this Idempotency-Key is still being processed")
+)
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl}
+}
+
+// Begin reserves key within scope for a new request. When the key was used
+// before it returns the stored response and replay is true, a key reused for
+// a different request or one still in flight is an error.
+func (s *idempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (model.IdempotencyKey, bool, error) {
+	now := time.Now().UTC()
+	record, reserved, err := s.repo.Reserve(ctx, model.IdempotencyKey{
+		ID:          uuid.New(),
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.ttl),
+	})
+	if err != nil {
+		return record, false, err
+	}
+	if reserved {
+		return record, false, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return record, false, ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return record, false, ErrIdempotencyKeyInProgress
+	}
+	return record, true, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, record model.IdempotencyKey) error {
+	return s.repo.Complete(ctx, record)
+}
+
+// Abandon forgets a reservation whose request failed so it can be retried
+func (s *idempotencyService) Abandon(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Release(ctx, id)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	models "homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+type ImportMode string
+
+const (
+	// ImportAtomic stores every row or none of them
+	ImportAtomic ImportMode = "atomic"
+	// ImportBestEffort stores the valid rows and reports the others
+	ImportBestEffort ImportMode = "best_effort"
+)
+
+type ImportOptions struct {
+	Mode   ImportMode `json:"mode"`
+	DryRun bool       `json:"dry_run"`
+}
+
+// ImportRow is one parsed input row. A row that could not be parsed or
+// validated carries its errors instead of a product.
+type ImportRow struct {
+	Product models.Product
+	Errors  []FieldError
+}
+
+// ImportSource passes the rows of an import to fn in input order, stopping
+// at the first error fn returns
+type ImportSource func(fn func(ImportRow) error) error
+
+// ImportRows is the source of rows already in memory
+func ImportRows(rows []ImportRow) ImportSource {
+	return func(fn func(ImportRow) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RowError lists the errors of one row, rows are numbered from 1 in input
+// order, not counting a CSV header
+type RowError struct {
+	Row    int          `json:"row"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportReport is the outcome of an import. Valid counts the rows without
+// errors, Imported the rows actually stored.
+type ImportReport struct {
+	ImportOptions
+	Total    int        `json:"total"`
+	Valid    int        `json:"valid"`
+	Failed   int        `json:"failed"`
+	Imported int        `json:"imported"`
+	Errors   []RowError `json:"errors"`
+}
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// ImportJob is an import running in the background. Report is set once the
+// job has completed, Error when it could not run at all.
+type ImportJob struct {
+	ID         uuid.UUID     `json:"id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Status     JobStatus     `json:"status"`
+	Rows       int           `json:"rows"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type ImportService interface {
+	ImportProducts(ctx context.Context, actor Actor, source ImportSource, opts ImportOptions) (ImportReport, error)
+	// StartProductImport reads source in the background, rows is its
+	// number of rows
+	StartProductImport(ctx context.Context, actor Actor, source ImportSource, rows int, opts ImportOptions) ImportJob
+	GetImportJob(ctx context.Context, actor Actor, id uuid.UUID) (ImportJob, error)
+	// Wait blocks until the running jobs finish or ctx is done
+	Wait(ctx context.Context) error
+}
+
+var ErrImportJobNotFound = NotFound("import_job_not_found", "import job not found")
+
+// importService keeps jobs in memory, a job still running when shutdown
+// stops waiting is lost. A finished job is forgotten retention after it
+// finished.
+type importService struct {
+	repo      repository.ProductRepository
+	retention time.Duration
+
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*ImportJob
+
+	running sync.WaitGroup
+}
+
+func NewImportService(repo repository.ProductRepository, retention time.Duration) ImportService {
+	return &importService{repo: repo, retention: retention, jobs: make(map[uuid.UUID]*ImportJob)}
+}
+
+// errInvalidRows rolls back an atomic import with rows that failed
+// validation
+var errInvalidRows = errors.New("invalid rows")
+
+// ImportProducts stores the rows for the caller as they are read. In atomic
+// mode nothing is stored when any row has an error.
+func (s *importService) ImportProducts(ctx context.Context, actor Actor, source ImportSource, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{ImportOptions: opts, Errors: []RowError{}}
+
+	invalid := false
+	err := s.repo.CreateMany(ctx, actor.UserID, repository.ImportOptions{
+		Atomic: opts.Mode == ImportAtomic,
+		DryRun: opts.DryRun,
+	}, func(create func(*models.Product) error) error {
+		err := source(func(row ImportRow) error {
+			report.Total++
+			if row.Errors == nil {
+				if err := validatePrice(row.Product.Price); err != nil {
+					row.Errors = []FieldError{{Field: "price", Code: ErrInvalidPrice.Code, Message: ErrInvalidPrice.Message}}
+				}
+			}
+			if row.Errors != nil {
+				invalid = true
+			} else {
+				product := row.Product
+				product.ID = uuid.New()
+				if err := create(&product); err != nil {
+					row.Errors = []FieldError{rowError(ctx, err)}
+				}
+			}
+			if row.Errors != nil {
+				report.Errors = append(report.Errors, RowError{Row: report.Total, Errors: row.Errors})
+			}
+			return nil
+		})
+		// an atomic import that failed validation is still checked against
+		// the database so the report lists every failing row
+		if err == nil && invalid && opts.Mode == ImportAtomic {
+			return errInvalidRows
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, errInvalidRows) {
+		return report, translate(err, ErrUserNotFound)
+	}
+
+	report.Failed = len(report.Errors)
+	report.Valid = report.Total - report.Failed
+	switch {
+	case opts.DryRun:
+	case opts.Mode == ImportAtomic && report.Failed > 0:
+	default:
+		report.Imported = report.Valid
+	}
+	return report, nil
+}
+
+// StartProductImport runs ImportProducts in the background, the job can be
+// followed with GetImportJob
+func (s *importService) StartProductImport(ctx context.Context, actor Actor, source ImportSource, rows int, opts ImportOptions) ImportJob {
+	job := &ImportJob{
+		ID:        uuid.New(),
+		UserID:    actor.UserID,
+		Status:    JobPending,
+		Rows:      rows,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.prune()
+	s.jobs[job.ID] = job
+	started := *job
+	s.mu.Unlock()
+
+	// the job outlives the request but keeps its values, such as the
+	// audit actor
+	ctx = context.WithoutCancel(ctx)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.update(job.ID, func(job *ImportJob) { job.Status = JobRunning })
+		report, err := s.ImportProducts(ctx, actor, source, opts)
+		s.update(job.ID, func(job *ImportJob) {
+			now := time.Now().UTC()
+			job.FinishedAt = &now
+			if err != nil {
+				slog.ErrorContext(ctx, "import job failed", "job_id", job.ID, "error", err)
+				job.Status = JobFailed
+				job.Error = publicMessage(err)
+				return
+			}
+			job.Status = JobCompleted
+			job.Report = &report
+		})
+	}()
+	return started
+}
+
+// GetImportJob returns a copy of the job, callers only see their own jobs
+// unless they are admins
+func (s *importService) GetImportJob(ctx context.Context, actor Actor, id uuid.UUID) (ImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || (!actor.IsAdmin() && job.UserID != actor.UserID) {
+		return ImportJob{}, ErrImportJobNotFound
+	}
+	return *job, nil
+}
+
+func (s *importService) update(id uuid.UUID, fn func(job *ImportJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// prune forgets finished jobs past the retention, s.mu must be held
+func (s *importService) prune() {
+	cutoff := time.Now().Add(-s.retention)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// rowError describes a row the database rejected
+func rowError(ctx context.Context, err error) FieldError {
+	var domain *Error
+	if errors.As(translate(err, ErrUserNotFound), &domain) {
+		return FieldError{Code: domain.Code, Message: domain.Message}
+	}
+	slog.ErrorContext(ctx, "failed to import row", "error", err)
+	return FieldError{Code: "internal_error", Message: "the row could not be stored"}
+}
+
+// publicMessage hides the text of errors that are not domain errors
+func publicMessage(err error) string {
+	var domain *Error
+	if errors.As(err, &domain) {
+		return domain.Message
+	}
+	return "the import could not be run"
+}
+
+func (s *importService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,44 +1,136 @@
 package services
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	models "homework1/internal/models"
 	"homework1/internal/repository"
 )
 
 type ProductService interface {
-	GetAllProducts() ([]models.Product, error)
-	GetProductById(id uuid.UUID) (models.Product, error)
-	CreateProduct(product models.Product) (models.Product, error)
-	UpdateProduct(id uuid.UUID, product models.Product) (models.Product, error)
-	DeleteProduct(id uuid.UUID) error
+	GetAllProducts(ctx context.Context, filter repository.ProductFilter, page repository.Page) ([]models.Product, string, error)
+	ExportProducts(ctx context.Context, filter repository.ProductFilter, fn func([]models.Product) error) error
+	SearchProducts(ctx context.Context, query string, filter repository.ProductFilter, limit, offset int) ([]repository.ProductMatch, error)
+	GetProductById(ctx context.Context, id uuid.UUID) (models.Product, error)
+	CreateProduct(ctx context.Context, actor Actor, product models.Product) (models.Product, error)
+	UpdateProduct(ctx context.Context, actor Actor, id uuid.UUID, version int64, product models.Product) (models.Product, error)
+	DeleteProduct(ctx context.Context, actor Actor, id uuid.UUID, version int64) error
+	RestoreProduct(ctx context.Context, actor Actor, id uuid.UUID) (models.Product, error)
+	PurgeProducts(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetProductHistory(ctx context.Context, id uuid.UUID) ([]models.HistoryEntry, error)
+	GetProductAsOf(ctx context.Context, id uuid.UUID, at time.Time) (models.Product, error)
 }
 
+var (
+	ErrNotProductOwner = Forbidden("not_product_owner", "only the product owner or an admin may modify this product")
+	ErrInvalidPrice    = Validation("invalid_price", "price must be a positive amount in a supported currency")
+)
+
 type productService struct {
-	repo repository.ProductRepository
+	repo     repository.ProductRepository
+	searcher repository.ProductSearcher
+}
+
+func NewProductService(repo repository.ProductRepository, searcher repository.ProductSearcher) ProductService {
+	return &productService{repo: repo, searcher: searcher}
 }
 
-func NewProductService(repo repository.ProductRepository) ProductService {
-	return &productService{repo: repo}
+func (ps *productService) GetAllProducts(ctx context.Context, filter repository.ProductFilter, page repository.Page) ([]models.Product, string, error) {
+	products, next, err := ps.repo.GetAll(ctx, filter, page)
+	return products, next, translate(err, ErrProductNotFound)
 }
 
-func (ps *productService) GetAllProducts() ([]models.Product, error) {
-	return ps.repo.GetAll()
+// ExportProducts streams the products matching filter to fn in batches
+func (ps *productService) ExportProducts(ctx context.Context, filter repository.ProductFilter, fn func([]models.Product) error) error {
+	return translate(ps.repo.Export(ctx, filter, fn), ErrProductNotFound)
 }
 
-func (ps *productService) GetProductById(id uuid.UUID) (models.Product, error) {
-	return ps.repo.GetById(id)
+func (ps *productService) SearchProducts(ctx context.Context, query string, filter repository.ProductFilter, limit, offset int) ([]repository.ProductMatch, error) {
+	matches, err := ps.searcher.Search(ctx, query, filter, limit, offset)
+	return matches, translate(err, ErrProductNotFound)
 }
 
-func (ps *productService) CreateProduct(product models.Product) (models.Product, error) {
+func (ps *productService) GetProductById(ctx context.Context, id uuid.UUID) (models.Product, error) {
+	product, err := ps.repo.GetById(ctx, id)
+	return product, translate(err, ErrProductNotFound)
+}
+
+// CreateProduct always assigns the product to the caller
+func (ps *productService) CreateProduct(ctx context.Context, actor Actor, product models.Product) (models.Product, error) {
+	if err := validatePrice(product.Price); err != nil {
+		return product, err
+	}
 	product.ID = uuid.New()
-	return ps.repo.Create(product)
+	product.UserID = actor.UserID
+	product, err := ps.repo.Create(ctx, product)
+	return product, translate(err, ErrUserNotFound)
+}
+
+// UpdateProduct fails with repository.ErrVersionConflict unless the product
+// is still at version, 0 skips the check
+func (ps *productService) UpdateProduct(ctx context.Context, actor Actor, id uuid.UUID, version int64, updatedProduct models.Product) (models.Product, error) {
+	if err := validatePrice(updatedProduct.Price); err != nil {
+		return updatedProduct, err
+	}
+	if err := ps.checkOwner(ctx, actor, id); err != nil {
+		return models.Product{}, err
+	}
+	product, err := ps.repo.Update(ctx, id, version, updatedProduct)
+	return product, translate(err, ErrProductNotFound)
+}
+
+func (ps *productService) DeleteProduct(ctx context.Context, actor Actor, id uuid.UUID, version int64) error {
+	if err := ps.checkOwner(ctx, actor, id); err != nil {
+		return err
+	}
+	return translate(ps.repo.Delete(ctx, id, version), ErrProductNotFound)
+}
+
+func (ps *productService) RestoreProduct(ctx context.Context, actor Actor, id uuid.UUID) (models.Product, error) {
+	product, err := ps.repo.GetDeletedById(ctx, id)
+	if err != nil {
+		return product, translate(err, ErrProductNotFound)
+	}
+	if !actor.IsAdmin() && product.UserID != actor.UserID {
+		return models.Product{}, ErrNotProductOwner
+	}
+	product, err = ps.repo.Restore(ctx, id)
+	return product, translate(err, ErrProductNotFound)
+}
+
+func (ps *productService) PurgeProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	n, err := ps.repo.Purge(ctx, deletedBefore)
+	return n, translate(err, ErrProductNotFound)
+}
+
+func (ps *productService) GetProductHistory(ctx context.Context, id uuid.UUID) ([]models.HistoryEntry, error) {
+	entries, err := ps.repo.History(ctx, id)
+	return entries, translate(err, ErrProductNotFound)
+}
+
+// GetProductAsOf returns the product as it was at the given time, including
+// versions of products deleted since
+func (ps *productService) GetProductAsOf(ctx context.Context, id uuid.UUID, at time.Time) (models.Product, error) {
+	product, err := ps.repo.AsOf(ctx, id, at)
+	return product, translate(err, ErrProductNotFound)
 }
 
-func (ps *productService) UpdateProduct(id uuid.UUID, updatedProduct models.Product) (models.Product, error) {
-	return ps.repo.Update(id, updatedProduct)
+func (ps *productService) checkOwner(ctx context.Context, actor Actor, id uuid.UUID) error {
+	product, err := ps.repo.GetById(ctx, id)
+	if err != nil {
+		return translate(err, ErrProductNotFound)
+	}
+	if !actor.IsAdmin() && product.UserID != actor.UserID {
+		return ErrNotProductOwner
+	}
+	return nil
 }
 
-func (ps *productService) DeleteProduct(id uuid.UUID) error {
-	return ps.repo.Delete(id)
+func validatePrice(price models.Money) error {
+	if !price.Currency.Valid() || price.Amount <= 0 {
+		return ErrInvalidPrice
+	}
+	return nil
 }
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"homework1/internal/repository"
+)
+
+func TestForgedCursorIsBadRequest(t *testing.T) {
+	db := newTestDB(t)
+	products := NewProductService(repository.NewProductRepository(db), nil)
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"price","v":{"$gt":0},"id":"x"}`))
+
+	_, _, err := products.GetAllProducts(context.Background(), repository.ProductFilter{}, repository.Page{Sort: "price", Cursor: cursor})
+	var domain *Error
+	if !errors.Is(err, ErrInvalidCursor) || !errors.As(err, &domain) || domain.Kind != KindBadRequest {
+		t.Errorf("forged cursor: %v, want ErrInvalidCursor as a bad request", err)
+	}
+}
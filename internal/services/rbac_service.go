@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+type RBACService interface {
+	HasPermission(role, permission string) bool
+	GetRolePermissions() map[string][]string
+	Grant(ctx context.Context, role, permission string) error
+	Revoke(ctx context.Context, role, permission string) error
+	RecordDenial(ctx context.Context, denial model.AccessDenial) error
+	GetDenials(ctx context.Context, limit int) ([]model.AccessDenial, error)
+}
+
+var (
+	ErrRoleNotFound       = NotFound("role_not_found", "unknown role")
+	ErrPermissionNotFound = NotFound("permission_not_found", "unknown permission")
+	ErrProtectedGrant     = Conflict("protected_grant", "admins cannot lose rbac:manage")
+)
+
+// DefaultRolePermissions is seeded into an empty role-permission table
+var DefaultRolePermissions = map[string][]string{
+	model.RoleAdmin: model.Permissions,
+	model.RoleSeller: {
+		model.PermProductsRead, model.PermProductsCreate,
+		model.PermProductsUpdate, model.PermProductsDelete,
+	},
+	model.RoleCustomer: {model.PermProductsRead},
+}
+
+// rbacService keeps the role-permission table in memory, it is small and
+// read on every guarded request
+type rbacService struct {
+	repo repository.RBACRepository
+
+	mu    sync.RWMutex
+	table map[string]map[string]bool
+}
+
+func NewRBACService(ctx context.Context, repo repository.RBACRepository) (RBACService, error) {
+	var defaults []model.RolePermission
+	for role, permissions := range DefaultRolePermissions {
+		for _, permission := range permissions {
+			defaults = append(defaults, model.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	if err := repo.Seed(ctx, defaults); err != nil {
+		return nil, err
+	}
+
+	s := &rbacService{repo: repo}
+	if err := s.reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *rbacService) reload(ctx context.Context) error {
+	rows, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	table := map[string]map[string]bool{}
+	for _, row := range rows {
+		if table[row.Role] == nil {
+			table[row.Role] = map[string]bool{}
+		}
+		table[row.Role][row.Permission] = true
+	}
+
+	s.mu.Lock()
+	s.table = table
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *rbacService) HasPermission(role, permission string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.table[role][permission]
+}
+
+func (s *rbacService) GetRolePermissions() map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := map[string][]string{}
+	for _, role := range model.Roles {
+		permissions := []string{}
+		for permission := range s.table[role] {
+			permissions = append(permissions, permission)
+		}
+		sort.Strings(permissions)
+		result[role] = permissions
+	}
+	return result
+}
+
+func (s *rbacService) Grant(ctx context.Context, role, permission string) error {
+	if err := validateGrant(role, permission); err != nil {
+		return err
+	}
+	if err := s.repo.Grant(ctx, role, permission); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+func (s *rbacService) Revoke(ctx context.Context, role, permission string) error {
+	if err := validateGrant(role, permission); err != nil {
+		return err
+	}
+	if role == model.RoleAdmin && permission == model.PermRBACManage {
+		return ErrProtectedGrant
+	}
+	if err := s.repo.Revoke(ctx, role, permission); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+func (s *rbacService) RecordDenial(ctx context.Context, denial model.AccessDenial) error {
+	denial.ID = uuid.New()
+	return s.repo.RecordDenial(ctx, denial)
+}
+
+func (s *rbacService) GetDenials(ctx context.Context, limit int) ([]model.AccessDenial, error) {
+	return s.repo.GetDenials(ctx, limit)
+}
+
+func validateGrant(role, permission string) error {
+	if !model.IsRole(role) {
+		return ErrRoleNotFound
+	}
+	if !model.IsPermission(permission) {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
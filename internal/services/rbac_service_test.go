@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"homework1/internal/models"
+	"homework1/internal/repository"
+)
+
+func TestRoleTable(t *testing.T) {
+	ctx := context.Background()
+	rbac, err := NewRBACService(ctx, repository.NewRBACRepository(newTestDB(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		role    string
+		allowed []string
+	}{
+		{model.RoleAdmin, model.Permissions},
+		{model.RoleSeller, []string{model.PermProductsRead, model.PermProductsCreate, model.PermProductsUpdate, model.PermProductsDelete}},
+		{model.RoleCustomer, []string{model.PermProductsRead}},
+		// unauthenticated callers have no role
+		{"", nil},
+		{"owner", nil},
+	}
+	for _, tt := range tests {
+		for _, permission := range model.Permissions {
+			want := false
+			for _, p := range tt.allowed {
+				want = want || p == permission
+			}
+			if got := rbac.HasPermission(tt.role, permission); got != want {
+				t.Errorf("%q has %s: %v, want %v", tt.role, permission, got, want)
+			}
+		}
+	}
+}
+
+func TestGrantRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewRBACRepository(newTestDB(t))
+	rbac, err := NewRBACService(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rbac.Grant(ctx, model.RoleCustomer, model.PermProductsCreate); err != nil {
+		t.Fatal(err)
+	}
+	if !rbac.HasPermission(model.RoleCustomer, model.PermProductsCreate) {
+		t.Error("a granted permission is not in effect")
+	}
+	if err := rbac.Revoke(ctx, model.RoleSeller, model.PermProductsDelete); err != nil {
+		t.Fatal(err)
+	}
+	if rbac.HasPermission(model.RoleSeller, model.PermProductsDelete) {
+		t.Error("a revoked permission is still in effect")
+	}
+
+	// the table is kept, seeding only fills an empty one
+	reloaded, err := NewRBACService(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.HasPermission(model.RoleCustomer, model.PermProductsCreate) || reloaded.HasPermission(model.RoleSeller, model.PermProductsDelete) {
+		t.Error("changes to the table did not survive a restart")
+	}
+
+	for _, tt := range []struct {
+		role, permission string
+		revoke           bool
+		want             error
+	}{
+		{"owner", model.PermProductsRead, false, ErrRoleNotFound},
+		{model.RoleSeller, "products:sell", false, ErrPermissionNotFound},
+		{"owner", model.PermProductsRead, true, ErrRoleNotFound},
+		{model.RoleAdmin, model.PermRBACManage, true, ErrProtectedGrant},
+	} {
+		change := rbac.Grant
+		if tt.revoke {
+			change = rbac.Revoke
+		}
+		if err := change(ctx, tt.role, tt.permission); !errors.Is(err, tt.want) {
+			t.Errorf("%s %s (revoke %v): %v, want %v", tt.role, tt.permission, tt.revoke, err, tt.want)
+		}
+	}
+	if !rbac.HasPermission(model.RoleAdmin, model.PermRBACManage) {
+		t.Error("admins lost rbac:manage")
+	}
+}
@@ -2,44 +2,140 @@ package services
 
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"homework1/internal/models"
+	"homework1/internal/password"
 	"homework1/internal/repository"
 )
 
 type UserService interface {
-	GetAllUsers() ([]model.User, error)
-	GetUserById(id uuid.UUID) (model.User, error)
-	CreateUser(user model.User) (model.User, error)
-	UpdateUser(id uuid.UUID,user model.User) (model.User, error)
-	DeleteUser(id uuid.UUID) error
+	GetAllUsers(ctx context.Context, filter repository.UserFilter, page repository.Page) ([]model.User, string, error)
+	ExportUsers(ctx context.Context, filter repository.UserFilter, fn func([]model.User) error) error
+	GetUserById(ctx context.Context, id uuid.UUID) (model.User, error)
+	CreateUser(ctx context.Context, user model.User) (model.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, version int64, user model.User) (model.User, error)
+	DeleteUser(ctx context.Context, actor Actor, id uuid.UUID, version int64) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (model.User, error)
+	PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetUserHistory(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error)
+	Authenticate(ctx context.Context, email, plain string) (model.User, error)
 }
 
+var (
+	ErrInvalidCredentials = Unauthorized("invalid_credentials", "invalid email or password")
+	ErrUnknownRole        = Validation("unknown_role", "unknown role")
+)
+
 type userService struct {
-	repo repository.UserRepository
+	repo          repository.UserRepository
+	productPolicy repository.ProductPolicy
+}
+
+// NewUserService takes the policy applied to a user's products on delete,
+// products are reassigned to the user performing the delete
+func NewUserService(repo repository.UserRepository, productPolicy repository.ProductPolicy) UserService {
+	return &userService{repo: repo, productPolicy: productPolicy}
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+func (s *userService) GetAllUsers(ctx context.Context, filter repository.UserFilter, page repository.Page) ([]model.User, string, error) {
+	users, next, err := s.repo.GetAll(ctx, filter, page)
+	return users, next, translate(err, ErrUserNotFound)
 }
 
-func (s *userService) GetAllUsers() ([]model.User, error) {
-	return s.repo.GetAll()
+// ExportUsers streams the users matching filter to fn in batches, without
+// their passwords or products
+func (s *userService) ExportUsers(ctx context.Context, filter repository.UserFilter, fn func([]model.User) error) error {
+	return translate(s.repo.Export(ctx, filter, fn), ErrUserNotFound)
 }
 
-func (s *userService) GetUserById(id uuid.UUID) (model.User, error) {
-	return s.repo.GetById(id)
+func (s *userService) GetUserById(ctx context.Context, id uuid.UUID) (model.User, error) {
+	user, err := s.repo.GetById(ctx, id)
+	return user, translate(err, ErrUserNotFound)
 }
 
-func (s *userService) CreateUser(user model.User) (model.User, error) {
+func (s *userService) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	if user.Role == "" {
+		user.Role = model.RoleCustomer
+	}
+	if !model.IsRole(user.Role) {
+		return user, ErrUnknownRole
+	}
+
 	user.ID = uuid.New()
-	return s.repo.Create(user)
+
+	hash, err := password.Hash(user.Password)
+	if err != nil {
+		return user, err
+	}
+	user.Password = hash
+
+	user, err = s.repo.Create(ctx, user)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return user, ErrEmailTaken.wrap(err)
+	}
+	return user, translate(err, ErrUserNotFound)
+}
+
+// UpdateUser only touches the password when a new one is supplied. It fails
+// with repository.ErrVersionConflict unless the user is still at version, 0
+// skips the check.
+func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, version int64, user model.User) (model.User, error) {
+	if user.Password != "" {
+		hash, err := password.Hash(user.Password)
+		if err != nil {
+			return user, err
+		}
+		user.Password = hash
+	}
+	user, err := s.repo.Update(ctx, id, version, user)
+	return user, translate(err, ErrUserNotFound)
 }
 
-func (s *userService) UpdateUser(id uuid.UUID, user model.User) (model.User, error) {
-	return s.repo.Update(id,user)
+func (s *userService) DeleteUser(ctx context.Context, actor Actor, id uuid.UUID, version int64) error {
+	return translate(s.repo.Delete(ctx, id, version, s.productPolicy, actor.UserID), ErrUserNotFound)
 }
 
-func (s *userService) DeleteUser(id uuid.UUID) error {
-	return s.repo.Delete(id)
+func (s *userService) RestoreUser(ctx context.Context, id uuid.UUID) (model.User, error) {
+	user, err := s.repo.Restore(ctx, id)
+	return user, translate(err, ErrUserNotFound)
+}
+
+func (s *userService) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	n, err := s.repo.Purge(ctx, deletedBefore)
+	return n, translate(err, ErrUserNotFound)
+}
+
+func (s *userService) GetUserHistory(ctx context.Context, id uuid.UUID) ([]model.HistoryEntry, error) {
+	entries, err := s.repo.History(ctx, id)
+	return entries, translate(err, ErrUserNotFound)
+}
+
+// Authenticate checks the credentials and upgrades the stored hash when it
+// was made with older parameters or the password is still plain text
+func (s *userService) Authenticate(ctx context.Context, email, plain string) (model.User, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return user, ErrInvalidCredentials
+	}
+
+	ok, err := password.Verify(plain, user.Password)
+	if err != nil || !ok {
+		return user, ErrInvalidCredentials
+	}
+
+	if password.NeedsRehash(user.Password) {
+		if hash, err := password.Hash(plain); err == nil {
+			user.Password = hash
+			if updated, err := s.repo.Update(ctx, user.ID, user.Version, user); err == nil {
+				user = updated
+			}
+		}
+	}
+
+	return user, nil
 }
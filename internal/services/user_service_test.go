@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"homework1/internal/models"
+	"homework1/internal/password"
+	"homework1/internal/repository"
+)
+
+// TestAuthenticateUpgradesLegacyPassword logs in as a user whose password is
+// still stored in plain text, like the admin of the shipped homework1.db
+func TestAuthenticateUpgradesLegacyPassword(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewUserRepository(newTestDB(t))
+	legacy, err := repo.Create(ctx, model.User{
+		ID: uuid.New(), FirstName: "John", LastName: "Doer", Email: "john.doer@example.com",
+		Password: "password123", Role: model.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewUserService(repo, repository.RestrictProducts)
+
+	if _, err := users.Authenticate(ctx, legacy.Email, "password124"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: %v, want ErrInvalidCredentials", err)
+	}
+	if stored, _ := repo.GetById(ctx, legacy.ID); stored.Password != "password123" {
+		t.Fatalf("a failed login replaced the stored password with %q", stored.Password)
+	}
+
+	if _, err := users.Authenticate(ctx, legacy.Email, "password123"); err != nil {
+		t.Fatalf("legacy password: %v", err)
+	}
+	stored, err := repo.GetById(ctx, legacy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password == "password123" || password.NeedsRehash(stored.Password) {
+		t.Fatalf("stored password %q was not rehashed", stored.Password)
+	}
+	if ok, err := password.Verify("password123", stored.Password); !ok || err != nil {
+		t.Errorf("the new hash does not verify: %v, %v", ok, err)
+	}
+
+	// the plain text is gone, the hash itself is not a password
+	if _, err := users.Authenticate(ctx, legacy.Email, stored.Password); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("logging in with the hash: %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := users.Authenticate(ctx, legacy.Email, "password123"); err != nil {
+		t.Errorf("login after the upgrade: %v", err)
+	}
+}
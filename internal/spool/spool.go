@@ -0,0 +1,93 @@
+// Package spool buffers request bodies that may be too large to keep in
+// memory.
+package spool
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// Buffer keeps what is written to it in memory up to a limit and moves it to
+// a temporary file once it grows past it. The file is unlinked as soon as it
+// is created where the system allows it, so a process that exits without
+// Close leaves nothing behind; elsewhere Close removes it.
+//
+// A failed write fails every later one, Err tells it apart from an error of
+// the reader being copied from.
+type Buffer struct {
+	limit int64
+	size  int64
+	mem   bytes.Buffer
+	file  *os.File
+	// unlinked is set once the file has no name left to remove
+	unlinked bool
+	err      error
+}
+
+func New(limit int64) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.file == nil && b.size+int64(len(p)) > b.limit {
+		file, err := os.CreateTemp("", "spool-*")
+		if err != nil {
+			b.err = err
+			return 0, err
+		}
+		b.file = file
+		b.unlinked = os.Remove(file.Name()) == nil
+		if _, err := file.Write(b.mem.Bytes()); err != nil {
+			b.err = err
+			return 0, err
+		}
+		b.mem = bytes.Buffer{}
+	}
+	var n int
+	if b.file != nil {
+		n, b.err = b.file.Write(p)
+	} else {
+		n, b.err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, b.err
+}
+
+// Err is the error of the first failed write
+func (b *Buffer) Err() error {
+	return b.err
+}
+
+// Len is the number of bytes written so far
+func (b *Buffer) Len() int64 {
+	return b.size
+}
+
+// Reader reads everything written so far from the start, each call returns a
+// new reader
+func (b *Buffer) Reader() io.Reader {
+	if b.file == nil {
+		return bytes.NewReader(b.mem.Bytes())
+	}
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+func (b *Buffer) Close() error {
+	b.mem = bytes.Buffer{}
+	if b.file == nil {
+		return nil
+	}
+	file := b.file
+	b.file = nil
+	err := file.Close()
+	if !b.unlinked {
+		if removeErr := os.Remove(file.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package spool
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestBufferMovesToFile(t *testing.T) {
+	b := New(8)
+	if _, err := b.Write([]byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+	if b.file != nil {
+		t.Fatal("a write within the limit created a file")
+	}
+	if _, err := b.Write([]byte("6789")); err != nil {
+		t.Fatal(err)
+	}
+	if b.file == nil {
+		t.Fatal("a write past the limit stayed in memory")
+	}
+	if _, err := os.Stat(b.file.Name()); !os.IsNotExist(err) {
+		t.Errorf("the file keeps its name while in use: %v", err)
+	}
+
+	// every reader starts from the beginning
+	for i := 0; i < 2; i++ {
+		data, err := io.ReadAll(b.Reader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "123456789" {
+			t.Errorf("read %q, want 123456789", data)
+		}
+	}
+	if b.Len() != 9 {
+		t.Errorf("Len %d, want 9", b.Len())
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferInMemory(t *testing.T) {
+	b := New(1 << 10)
+	if _, err := io.Copy(b, bytes.NewReader([]byte("small"))); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(b.Reader())
+	if string(data) != "small" || b.file != nil {
+		t.Errorf("read %q with file %v, want small from memory", data, b.file)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}